@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository/file"
+)
+
+const progressInterval = 5 * time.Second
+
+// backfill processes fixed block range for addresses into local store, e.g. to fill gaps found by verify-gaps
+func backfill(args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	from := fs.Int("from", 0, "First block of range")
+	to := fs.Int("to", 0, "Last block of range, inclusive")
+	chainID := fs.Uint64("chain-id", 0, "Chain to backfill, the first configured chain if zero")
+
+	var addresses addressesFlag
+	fs.Var(&addresses, "address", "Address to backfill, repeat flag or separate addresses by comma")
+	fs.Parse(args)
+
+	if *from <= 0 || *to < *from {
+		return fmt.Errorf("invalid range %d-%d, set --from and --to", *from, *to)
+	}
+
+	if len(addresses) == 0 {
+		return errors.New("at least one --address is required")
+	}
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Storage.Path == "" {
+		return errors.New("storage.path is not set, backfilled transactions would be lost on exit")
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	chainConf, client, err := openChain(ctx, cfg, *chainID)
+	if err != nil {
+		return err
+	}
+
+	repo, err := file.Open(cfg.Storage.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer repo.Close()
+
+	p := parser.New(chainConf, client, repo, events.NewBroker())
+	for _, address := range addresses {
+		p.Subscribe(address)
+	}
+
+	slog.Info("Backfilling", "chain", p.Name(), "from", *from, "to", *to, "addresses", len(addresses), "storage", cfg.Storage.Path)
+
+	var failed, saved int
+	lastProgress := time.Now()
+
+	for number := *from; number <= *to; number++ {
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted at block %d: %w", number, ctx.Err())
+		}
+
+		block, err := p.ProcessBlock(ctx, number)
+		if err != nil {
+			slog.Error("failed to process block", "number", number, "error", err)
+			failed++
+			continue
+		}
+
+		for _, txs := range block.Transactions {
+			saved += len(txs)
+		}
+
+		if time.Since(lastProgress) >= progressInterval {
+			slog.Info("Backfill progress", "block", number, "done", number-*from+1, "total", *to-*from+1, "transactions", saved)
+			lastProgress = time.Now()
+		}
+	}
+
+	slog.Info("Backfill finished", "blocks", *to-*from+1, "failed", failed, "transactions", saved)
+
+	if failed > 0 {
+		return fmt.Errorf("%d blocks failed, find them with verify-gaps and run backfill again", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+)
+
+// verifyGaps prints ranges of blocks missing in local store, fails if there are any
+func verifyGaps(args []string) error {
+	fs := flag.NewFlagSet("verify-gaps", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	chainID := fs.Uint64("chain-id", 0, "Chain to verify, the first configured chain if zero")
+	from := fs.Int("from", 0, "First block to verify, the first processed block if zero")
+	to := fs.Int("to", 0, "Last block to verify, the last processed block if zero")
+	fs.Parse(args)
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	repo, err := openLocalStore(cfg.Storage)
+	if err != nil {
+		return err
+	}
+
+	id, err := localChainID(ctx, cfg, *chainID)
+	if err != nil {
+		return err
+	}
+
+	ranges, err := repo.ProcessedRanges(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get processed blocks: %w", err)
+	}
+
+	if len(ranges) == 0 {
+		return errors.New("no processed blocks in store")
+	}
+
+	first, last := *from, *to
+	if first == 0 {
+		first = ranges[0].From
+	}
+	if last == 0 {
+		last = ranges[len(ranges)-1].To
+	}
+
+	gaps := repository.Gaps(ranges, first, last)
+	if len(gaps) == 0 {
+		fmt.Printf("no gaps in blocks %d-%d of chain %d\n", first, last, id)
+		return nil
+	}
+
+	missing := 0
+	for _, gap := range gaps {
+		missing += gap.Len()
+		fmt.Printf("%d-%d (%d blocks)\n", gap.From, gap.To, gap.Len())
+	}
+
+	return fmt.Errorf("found %d gaps with %d missing blocks in %d-%d, fill them with backfill --from --to", len(gaps), missing, first, last)
+}
@@ -2,80 +2,229 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/avelex/blockchain-parser/config"
-	"github.com/avelex/blockchain-parser/internal/api"
 	"github.com/avelex/blockchain-parser/internal/ethclient"
-	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/file"
 	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/types"
 )
 
-var configFile = flag.String("config", "config.yaml", "Config file path")
+const (
+	chainIDTimeout = 10 * time.Second
+	// interval between chain id requests to RPC unreachable at startup
+	chainRetryInterval = 10 * time.Second
+)
+
+var errChainIDMismatch = errors.New("chain id mismatch")
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{name: "serve", usage: "parse chains and serve API, the default command", run: serve},
+	{name: "backfill", usage: "process block range for addresses into local store", run: backfill},
+	{name: "scan", usage: "extract transactions of addresses from block range into file without storage", run: scan},
+	{name: "query", usage: "print transactions of address from local store", run: query},
+	{name: "export", usage: "write transactions of addresses from local store as csv, jsonl or parquet", run: export},
+	{name: "snapshot", usage: "write snapshot of local store", run: takeSnapshot},
+	{name: "restore", usage: "seed local store from snapshot, instance must be stopped", run: restore},
+	{name: "status", usage: "print sync status of running instance", run: status},
+	{name: "verify-gaps", usage: "find blocks missing in local store", run: verifyGaps},
+}
 
 func main() {
-	flag.Parse()
+	name, args := "serve", os.Args[1:]
 
-	ctx, cancel := signal.NotifyContext(context.Background(),
-		syscall.SIGTERM,
-		syscall.SIGINT,
-	)
+	// flags without command start server, as before commands were added
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-	context.AfterFunc(ctx, func() {
-		slog.Info("Interrupt signal received, Stopping blockchain parser...")
-	})
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
 
-	defer cancel()
+		if err := cmd.run(args); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	cfg, err := config.LoadConfig(*configFile)
-	if err != nil {
-		slog.Error("Failed to load config", "error", err)
-		os.Exit(1)
+	fmt.Fprintf(os.Stderr, "unknown command %q, usage: %s <command> [flags]\n\n", name, os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.usage)
 	}
+	os.Exit(2)
+}
 
-	slog.Info("Loaded config", "port", cfg.Port, "rpc", cfg.RPC, "blocks_interval", cfg.BlocksInterval, "start_block", cfg.StartBlock)
+// configFlags are bound by every command, so all of them share the config loader
+type configFlags struct {
+	path      *string
+	overrides *config.Flags
+}
 
-	repo := memory.New()
-	client := ethclient.New(cfg.RPC)
-	parser := parser.New(cfg, client, repo)
-	handler := api.NewHandler(parser)
+func bindConfigFlags(fs *flag.FlagSet) *configFlags {
+	return &configFlags{
+		path:      fs.String("config", "config.yaml", "Config file path, empty to configure with environment and flags only"),
+		overrides: config.BindFlags(fs),
+	}
+}
 
-	mux := http.NewServeMux()
-	handler.Register(mux)
+func (f *configFlags) load() (config.Config, error) {
+	return config.Load(*f.path, os.LookupEnv, f.overrides)
+}
+
+// addressesFlag collects addresses from repeated or comma separated flag values
+type addressesFlag []types.Address
 
-	go func() {
-		slog.Info("Starting Blockchain Parser")
+func (a *addressesFlag) String() string {
+	return fmt.Sprint(*a)
+}
 
-		if err := parser.Start(ctx); err != nil {
-			slog.Error("Failed to start parser", "error", err)
+func (a *addressesFlag) Set(s string) error {
+	for _, raw := range strings.Split(s, ",") {
+		address, err := types.ParseAddress(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid address %q: %w", raw, err)
 		}
-	}()
+		*a = append(*a, address)
+	}
+	return nil
+}
+
+// signalContext is canceled on SIGINT or SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+}
 
-	server := http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.Port),
-		Handler: mux,
+// openRepository opens file repository if storage path is set, memory one otherwise
+func openRepository(conf config.StorageConfig) (repository.Repository, func() error, error) {
+	if conf.Path == "" {
+		return memory.New(), func() error { return nil }, nil
 	}
 
-	go func() {
-		slog.Info("Starting HTTP server", "port", cfg.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("Failed to start http server", "error", err)
+	repo, err := file.Open(conf.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open storage: %w", err)
+	}
+
+	return repo, repo.Close, nil
+}
+
+// openLocalStore opens storage of config without modifying it, instance may be running
+func openLocalStore(conf config.StorageConfig) (*file.Repository, error) {
+	if conf.Path == "" {
+		return nil, fmt.Errorf("storage.path is not set, transactions are kept in memory of running instance only")
+	}
+
+	return file.OpenReadOnly(conf.Path)
+}
+
+// openChain returns config and client of chain with chainID, the first chain if zero,
+// chain ID is validated against RPC or taken from it if not configured
+func openChain(ctx context.Context, cfg config.Config, chainID uint64) (config.ChainConfig, *ethclient.Client, error) {
+	chains := cfg.ChainConfigs()
+
+	chainConf := chains[0]
+	if chainID != 0 {
+		found := false
+		for _, c := range chains {
+			// chain ID may be not configured for single chain
+			if c.ChainID == chainID || (c.ChainID == 0 && len(chains) == 1) {
+				chainConf, found = c, true
+				break
+			}
+		}
+
+		if !found {
+			return config.ChainConfig{}, nil, fmt.Errorf("chain %d is not configured", chainID)
 		}
-	}()
 
-	<-ctx.Done()
+		chainConf.ChainID = chainID
+	}
+
+	client := ethclient.New(chainConf.RPC)
+
+	chainConf, err := resolveChainID(ctx, client, chainConf)
+	if err != nil {
+		return config.ChainConfig{}, nil, fmt.Errorf("failed to validate chain at %s: %w", client.Endpoint(), err)
+	}
+
+	return chainConf, client, nil
+}
+
+// localChainID returns chainID if set, otherwise ID of the first configured chain,
+// RPC is called only if it's not configured
+func localChainID(ctx context.Context, cfg config.Config, chainID uint64) (uint64, error) {
+	if chainID != 0 {
+		return chainID, nil
+	}
+
+	if id := cfg.ChainConfigs()[0].ChainID; id != 0 {
+		return id, nil
+	}
 
-	if err := server.Shutdown(context.Background()); err != nil {
-		slog.Warn("Failed to shutdown http server", "error", err)
+	chainConf, _, err := openChain(ctx, cfg, 0)
+	if err != nil {
+		return 0, err
 	}
 
-	slog.Info("Http server stopped")
+	return chainConf.ChainID, nil
+}
 
-	slog.Info("Blockchain parser stopped")
+// resolveChainID validates chain ID from config against RPC, or takes it from RPC if not set
+func resolveChainID(ctx context.Context, client *ethclient.Client, conf config.ChainConfig) (config.ChainConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, chainIDTimeout)
+	defer cancel()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return conf, fmt.Errorf("failed to get chain id: %w", err)
+	}
+
+	if conf.ChainID != 0 && conf.ChainID != chainID {
+		return conf, fmt.Errorf("%w, config %d, rpc %d", errChainIDMismatch, conf.ChainID, chainID)
+	}
+
+	conf.ChainID = chainID
+
+	return conf, nil
+}
+
+// waitChainID requests chain id from RPC every retry interval until it answers or ctx is done,
+// mismatched chain id is not retried
+func waitChainID(ctx context.Context, client *ethclient.Client, chainID uint64) error {
+	ticker := time.NewTicker(chainRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		_, err := resolveChainID(ctx, client, config.ChainConfig{ChainID: chainID})
+		if err == nil || errors.Is(err, errChainIDMismatch) {
+			return err
+		}
+
+		slog.Warn("RPC is still unreachable", "chain_id", chainID, "rpc", client.Endpoint(), "error", err)
+	}
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+)
+
+// query prints transactions of address from local store
+func query(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	chainID := fs.Uint64("chain-id", 0, "Chain of transactions, the first configured chain if zero")
+
+	var addresses addressesFlag
+	fs.Var(&addresses, "address", "Address to query")
+	fs.Parse(args)
+
+	if len(addresses) != 1 {
+		return errors.New("exactly one --address is required")
+	}
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	repo, err := openLocalStore(cfg.Storage)
+	if err != nil {
+		return err
+	}
+
+	id, err := localChainID(ctx, cfg, *chainID)
+	if err != nil {
+		return err
+	}
+
+	txs, err := repo.GetTransactions(ctx, id, addresses[0])
+	if err != nil {
+		return fmt.Errorf("no transactions of %s in store: %w", addresses[0], err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(txs)
+}
+
+// export writes transactions of addresses from local store as CSV, JSON lines or Parquet
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	chainID := fs.Uint64("chain-id", 0, "Chain of transactions, the first configured chain if zero")
+	output := fs.String("output", "", "Output file, stdout if empty")
+	formatName := fs.String("format", string(repository.FormatJSONL), "Output format: csv, jsonl or parquet")
+
+	var addresses addressesFlag
+	fs.Var(&addresses, "address", "Address to export, repeat flag or separate addresses by comma")
+	fs.Parse(args)
+
+	if len(addresses) == 0 {
+		return errors.New("at least one --address is required")
+	}
+
+	format, err := repository.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	repo, err := openLocalStore(cfg.Storage)
+	if err != nil {
+		return err
+	}
+
+	id, err := localChainID(ctx, cfg, *chainID)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %w", err)
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+
+	if err := repository.Export(ctx, repo, bw, format, id, addresses); err != nil {
+		return fmt.Errorf("failed to export: %w", err)
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// scanRecord is a line of scan output, transaction of address found in block
+type scanRecord struct {
+	Address types.Address `json:"address"`
+	Block   int           `json:"block"`
+	types.Transaction
+}
+
+// scan extracts transactions of addresses from fixed block range into output file and exits,
+// nothing is saved to storage
+func scan(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	from := fs.Int("from", 0, "First block of range")
+	to := fs.Int("to", 0, "Last block of range, inclusive")
+	chainID := fs.Uint64("chain-id", 0, "Chain to scan, the first configured chain if zero")
+	concurrency := fs.Int("concurrency", 16, "Number of blocks processed at once")
+	output := fs.String("output", "", "Output file of JSON lines")
+	addressesFile := fs.String("addresses-file", "", "File with address per line, in addition to --address")
+
+	var addresses addressesFlag
+	fs.Var(&addresses, "address", "Address to scan, repeat flag or separate addresses by comma")
+	fs.Parse(args)
+
+	if *from <= 0 || *to < *from {
+		return fmt.Errorf("invalid range %d-%d, set --from and --to", *from, *to)
+	}
+
+	if *concurrency <= 0 {
+		return errors.New("--concurrency must be positive")
+	}
+
+	if *output == "" {
+		return errors.New("--output is required")
+	}
+
+	if *addressesFile != "" {
+		if err := readAddresses(*addressesFile, &addresses); err != nil {
+			return err
+		}
+	}
+
+	if len(addresses) == 0 {
+		return errors.New("at least one --address or --addresses-file is required")
+	}
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	chainConf, client, err := openChain(ctx, cfg, *chainID)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create output: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+
+	// scan doesn't save transactions, repository stays empty
+	p := parser.New(chainConf, client, memory.New(), events.NewBroker())
+	for _, address := range addresses {
+		p.Subscribe(address)
+	}
+
+	total := *to - *from + 1
+	slog.Info("Scanning", "chain", p.Name(), "from", *from, "to", *to, "addresses", len(addresses), "concurrency", *concurrency, "output", *output)
+
+	var failed []int
+	var found int
+	start := time.Now()
+	lastProgress := start
+
+	err = p.Scan(ctx, *from, *to, *concurrency, func(number int, block parser.Block, err error) error {
+		if err != nil {
+			slog.Error("failed to scan block", "number", number, "error", err)
+			failed = append(failed, number)
+		}
+
+		// addresses are sorted, so output of the same range is the same between runs
+		for _, address := range slices.SortedFunc(maps.Keys(block.Transactions), types.Address.Compare) {
+			for _, tx := range block.Transactions[address] {
+				if err := encoder.Encode(scanRecord{Address: address, Block: number, Transaction: tx}); err != nil {
+					return fmt.Errorf("failed to write output: %w", err)
+				}
+				found++
+			}
+		}
+
+		if done := number - *from + 1; time.Since(lastProgress) >= progressInterval || done == total {
+			elapsed := time.Since(start)
+			rate := float64(done) / elapsed.Seconds()
+			eta := time.Duration(float64(total-done) / rate * float64(time.Second)).Round(time.Second)
+
+			slog.Info("Scan progress", "block", number, "done", done, "total", total, "failed", len(failed),
+				"transactions", found, "blocks_per_second", fmt.Sprintf("%.1f", rate), "eta", eta)
+			lastProgress = time.Now()
+		}
+
+		return nil
+	})
+	if err != nil {
+		// keep blocks scanned before interruption
+		w.Flush()
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	slog.Info("Scan finished", "blocks", total, "failed", len(failed), "transactions", found, "dur", time.Since(start).Round(time.Millisecond))
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d blocks failed, scan them again: %s", len(failed), formatRanges(failed))
+	}
+
+	return nil
+}
+
+// readAddresses appends addresses from file, empty lines and lines starting with # are skipped
+func readAddresses(path string, addresses *addressesFlag) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read addresses: %w", err)
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := addresses.Set(line); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// formatRanges joins ascending block numbers into ranges, e.g. 1-3,7
+func formatRanges(numbers []int) string {
+	var ranges []repository.BlockRange
+	for _, n := range numbers {
+		if last := len(ranges) - 1; last >= 0 && ranges[last].To == n-1 {
+			ranges[last].To = n
+			continue
+		}
+		ranges = append(ranges, repository.BlockRange{From: n, To: n})
+	}
+
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.From == r.To {
+			parts = append(parts, fmt.Sprint(r.From))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d-%d", r.From, r.To))
+	}
+
+	return strings.Join(parts, ",")
+}
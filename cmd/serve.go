@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/api"
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/types"
+	"github.com/avelex/blockchain-parser/internal/webhook"
+)
+
+const (
+	configPollInterval = 5 * time.Second
+	// RPC changed on reload is switched to only if it answers chain id within timeout
+	rpcProbeTimeout = 3 * time.Second
+)
+
+// serve runs parsers of all chains with API server until interrupted
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	checkConfig := fs.Bool("check-config", false, "Validate config and exit")
+	fs.Parse(args)
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	if *checkConfig {
+		fmt.Println("config is valid")
+		return nil
+	}
+
+	slog.SetLogLoggerLevel(cfg.Level())
+	slog.Info("Loaded config", "config", cfg.Redacted())
+
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		syscall.SIGTERM,
+		syscall.SIGINT,
+	)
+
+	context.AfterFunc(ctx, func() {
+		slog.Info("Interrupt signal received, Stopping blockchain parser...")
+	})
+
+	defer cancel()
+
+	repo, closeRepo, err := openRepository(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	defer closeRepo()
+
+	broker := events.NewBroker()
+
+	var (
+		clients      []*ethclient.Client
+		parsers      []*parser.BlockchainParser
+		chainParsers []parser.Parser
+		// parsers of unreachable chains are started once their RPC answers
+		unreachable []bool
+	)
+
+	declared := make(map[uint64]struct{})
+
+	for _, chainConf := range cfg.ChainConfigs() {
+		client := ethclient.New(chainConf.RPC)
+
+		chainConf, err := resolveChainID(ctx, client, chainConf)
+		// chain without configured id can't be routed until RPC answers, so it must be reachable
+		if err != nil && (chainConf.ChainID == 0 || errors.Is(err, errChainIDMismatch)) {
+			return fmt.Errorf("failed to validate chain %s at %s: %w", chainConf.Name, client.Endpoint(), err)
+		}
+		if err != nil {
+			slog.Warn("RPC is unreachable, chain is started once it answers", "chain_id", chainConf.ChainID,
+				"rpc", client.Endpoint(), "error", err)
+		}
+
+		if _, ok := declared[chainConf.ChainID]; ok {
+			return fmt.Errorf("chain %d is declared twice", chainConf.ChainID)
+		}
+		declared[chainConf.ChainID] = struct{}{}
+
+		p := parser.New(chainConf, client, repo, broker)
+
+		slog.Info("Parsing chain", "chain", p.Name(), "chain_id", chainConf.ChainID, "rpc", client.Endpoint(),
+			"blocks_interval", chainConf.BlocksInterval, "start_block", chainConf.StartBlock)
+
+		clients = append(clients, client)
+		parsers = append(parsers, p)
+		chainParsers = append(chainParsers, p)
+		unreachable = append(unreachable, err != nil)
+	}
+
+	chains := parser.NewChains(chainParsers...)
+
+	dispatcher, err := webhook.New(cfg.Webhook, broker)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook dispatcher: %w", err)
+	}
+
+	// restore addresses of persisted webhook subscriptions
+	for _, sub := range dispatcher.Subscriptions() {
+		chains.Subscribe(sub.Address)
+	}
+
+	keys, err := auth.NewStore(cfg.Auth.KeysPath, cfg.Auth.AdminKey)
+	if err != nil {
+		return fmt.Errorf("failed to create api keys store: %w", err)
+	}
+
+	// without admin key no other key can be created, so every authenticated route is unusable
+	if cfg.Auth.Enabled && !keys.HasAdmin() {
+		return fmt.Errorf("auth is enabled without admin key, set auth.admin_key or PARSER_AUTH_ADMIN_KEY")
+	}
+
+	// restore addresses subscribed by api keys
+	for _, address := range keys.AllAddresses() {
+		chains.Subscribe(address)
+	}
+
+	handler := api.NewHandler(chains, broker, dispatcher, keys, repo, cfg)
+
+	mux := http.NewServeMux()
+	handler.Register(mux)
+
+	for i, p := range parsers {
+		go func() {
+			if unreachable[i] {
+				if err := waitChainID(ctx, clients[i], p.ChainID()); err != nil {
+					slog.Error("Failed to validate chain", "chain", p.Name(), "error", err)
+					return
+				}
+			}
+
+			slog.Info("Starting Blockchain Parser", "chain", p.Name())
+
+			if err := p.Start(ctx); err != nil {
+				slog.Error("Failed to start parser", "chain", p.Name(), "error", err)
+			}
+		}()
+	}
+
+	if *conf.path != "" {
+		watcher := config.NewWatcher(*conf.path, configPollInterval, cfg, conf.load)
+
+		go func() {
+			slog.Info("Watching config file", "path", *conf.path)
+
+			reloader := newReloader(clients, parsers, handler, watcher.Update)
+
+			err := watcher.Start(ctx, func(prev, next config.Config) config.Config {
+				return reloader.apply(ctx, prev, next)
+			})
+			if err != nil {
+				slog.Error("Failed to watch config", "error", err)
+			}
+		}()
+	}
+
+	if retention := newRetention(cfg.Retention); retention.Enabled() {
+		compactor := repository.NewCompactor(repo, retention, cfg.Retention.Interval)
+
+		go func() {
+			slog.Info("Starting repository compactor", "interval", cfg.Retention.Interval)
+
+			if err := compactor.Start(ctx); err != nil {
+				slog.Error("Failed to start repository compactor", "error", err)
+			}
+		}()
+	}
+
+	dispatcherDone := make(chan struct{})
+
+	go func() {
+		defer close(dispatcherDone)
+
+		slog.Info("Starting webhook dispatcher")
+
+		if err := dispatcher.Start(ctx); err != nil {
+			slog.Error("Failed to start webhook dispatcher", "error", err)
+		}
+	}()
+
+	server := http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: mux,
+	}
+
+	go func() {
+		slog.Info("Starting HTTP server", "port", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Failed to start http server", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		slog.Warn("Failed to shutdown http server", "error", err)
+	}
+
+	slog.Info("Http server stopped")
+
+	<-dispatcherDone
+	if err := dispatcher.Close(); err != nil {
+		slog.Warn("Failed to close webhook outbox", "error", err)
+	}
+
+	slog.Info("Blockchain parser stopped")
+
+	return nil
+}
+
+// newRetention creates retention from validated config
+func newRetention(conf config.RetentionConfig) repository.Retention {
+	addresses := make(map[types.Address]repository.RetentionPolicy, len(conf.Addresses))
+	for _, a := range conf.Addresses {
+		address, err := types.ParseAddress(a.Address)
+		if err != nil {
+			continue
+		}
+
+		addresses[address] = repository.RetentionPolicy{MaxAge: a.MaxAge, MaxTransactions: a.MaxTransactions}
+	}
+
+	policy := repository.RetentionPolicy{MaxAge: conf.MaxAge, MaxTransactions: conf.MaxTransactions}
+
+	return repository.NewRetention(policy, addresses, conf.MaxBytes)
+}
+
+// reloader applies live changes of reloaded config, chains are in the order of config
+type reloader struct {
+	clients []*ethclient.Client
+	parsers []*parser.BlockchainParser
+	handler *api.Handler
+	// update records RPC change applied after probe in running config
+	update func(ctx context.Context, update func(c *config.Config))
+
+	mu sync.Mutex
+	// number of the latest RPC probe of chain, results of older probes are dropped
+	probes []int
+}
+
+func newReloader(clients []*ethclient.Client, parsers []*parser.BlockchainParser, handler *api.Handler,
+	update func(ctx context.Context, update func(c *config.Config))) *reloader {
+	return &reloader{
+		clients: clients,
+		parsers: parsers,
+		handler: handler,
+		update:  update,
+		probes:  make([]int, len(parsers)),
+	}
+}
+
+// apply applies live changes and returns config they are applied to,
+// changed RPC is kept previous until probe of new endpoint succeeds
+func (r *reloader) apply(ctx context.Context, prev, next config.Config) config.Config {
+	slog.SetLogLoggerLevel(next.Level())
+	r.handler.SetRateLimits(next.RateLimit)
+
+	prevChains := prev.ChainConfigs()
+
+	for i, chainConf := range next.ChainConfigs() {
+		if chainConf.RPC != prevChains[i].RPC {
+			next.SetChainRPC(i, prevChains[i].RPC)
+
+			r.mu.Lock()
+			r.probes[i]++
+			probe := r.probes[i]
+			r.mu.Unlock()
+
+			go r.probeRPC(ctx, i, probe, chainConf.RPC)
+		}
+
+		if chainConf.BlocksInterval != prevChains[i].BlocksInterval {
+			r.parsers[i].SetBlocksInterval(chainConf.BlocksInterval)
+		}
+	}
+
+	return next
+}
+
+// probeRPC switches chain to new endpoint if it serves the same chain, otherwise parsed data is mixed
+func (r *reloader) probeRPC(ctx context.Context, i, probe int, rpc string) {
+	p := r.parsers[i]
+	candidate := ethclient.New(rpc)
+
+	probeCtx, cancel := context.WithTimeout(ctx, rpcProbeTimeout)
+	defer cancel()
+
+	_, err := resolveChainID(probeCtx, candidate, config.ChainConfig{ChainID: p.ChainID()})
+
+	r.mu.Lock()
+	latest := r.probes[i] == probe
+	if latest && err == nil {
+		r.clients[i].SetURL(rpc)
+	}
+	r.mu.Unlock()
+
+	if !latest {
+		slog.Info("RPC change is superseded by newer one", "chain", p.Name(), "rpc", candidate.Endpoint())
+		return
+	}
+
+	if err != nil {
+		slog.Error("Rejected RPC change, previous endpoint is kept", "chain", p.Name(), "rpc", candidate.Endpoint(), "error", err)
+		return
+	}
+
+	// watcher loop may wait for lock in apply, so running config is updated without it
+	r.update(ctx, func(c *config.Config) {
+		c.SetChainRPC(i, rpc)
+	})
+
+	slog.Info("RPC endpoint changed", "chain", p.Name(), "rpc", candidate.Endpoint())
+}
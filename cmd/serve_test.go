@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/api"
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/simnode"
+	"github.com/avelex/blockchain-parser/internal/webhook"
+)
+
+// newTestReloader creates reloader of a single chain 1 parsed from rpc, updates of running config are sent to channel
+func newTestReloader(t *testing.T, rpc string) (*reloader, <-chan func(c *config.Config)) {
+	t.Helper()
+
+	repo := memory.New()
+	broker := events.NewBroker()
+	client := ethclient.New(rpc)
+	p := parser.New(config.ChainConfig{ChainID: 1}, client, repo, broker)
+
+	conf := config.Default()
+
+	dispatcher, err := webhook.New(conf.Webhook, broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewStore("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := api.NewHandler(parser.NewChains(p), broker, dispatcher, keys, repo, conf)
+
+	updates := make(chan func(c *config.Config), 1)
+	r := newReloader([]*ethclient.Client{client}, []*parser.BlockchainParser{p}, handler,
+		func(ctx context.Context, update func(c *config.Config)) { updates <- update })
+
+	return r, updates
+}
+
+func newTestNode(t *testing.T, chainID uint64) string {
+	t.Helper()
+
+	server := httptest.NewServer(simnode.New(chainID, 100))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func Test_ReloaderApply(t *testing.T) {
+	current, same := newTestNode(t, 1), newTestNode(t, 1)
+
+	r, updates := newTestReloader(t, current)
+
+	prev := config.Default()
+	prev.RPC = current
+
+	next := prev
+	next.RPC = same
+	next.BlocksInterval = time.Second
+	next.RateLimit.PerIP.Rate = 5
+
+	applied := r.apply(context.Background(), prev, next)
+
+	// RPC is applied after probe, the rest right away
+	if applied.RPC != current || applied.BlocksInterval != time.Second || applied.RateLimit.PerIP.Rate != 5 {
+		t.Fatalf("applied config is not equal, want previous RPC only, got %+v", applied)
+	}
+
+	select {
+	case update := <-updates:
+		update(&applied)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("RPC change is not applied")
+	}
+
+	if applied.RPC != same {
+		t.Fatalf("RPC is not equal, want %s, got %s", same, applied.RPC)
+	}
+}
+
+func Test_ReloaderProbe(t *testing.T) {
+	current := newTestNode(t, 1)
+
+	// endpoint accepts connections and never answers
+	hang := make(chan struct{})
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	t.Cleanup(unreachable.Close)
+	t.Cleanup(func() { close(hang) })
+
+	testCases := []struct {
+		desc       string
+		rpc        string
+		superseded bool
+		wantRPC    string
+	}{
+		{
+			desc:    "Same chain",
+			rpc:     newTestNode(t, 1),
+			wantRPC: "",
+		},
+		{
+			desc:    "Another chain",
+			rpc:     newTestNode(t, 10),
+			wantRPC: current,
+		},
+		{
+			desc:    "Unreachable endpoint",
+			rpc:     unreachable.URL,
+			wantRPC: current,
+		},
+		{
+			desc:       "Superseded by newer change",
+			rpc:        newTestNode(t, 1),
+			superseded: true,
+			wantRPC:    current,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r, updates := newTestReloader(t, current)
+			if tC.superseded {
+				r.probes[0]++
+			}
+
+			start := time.Now()
+			r.probeRPC(context.Background(), 0, 0, tC.rpc)
+
+			if elapsed := time.Since(start); elapsed > rpcProbeTimeout+time.Second {
+				t.Fatalf("probe is not limited by timeout, took %s", elapsed)
+			}
+
+			want := tC.wantRPC
+			if want == "" {
+				want = tC.rpc
+			}
+
+			if got := r.clients[0].Endpoint(); got != ethclient.New(want).Endpoint() {
+				t.Fatalf("endpoint is not equal, want %s, got %s", want, got)
+			}
+
+			if switched := len(updates) > 0; switched != (want == tC.rpc) {
+				t.Fatalf("running config update is not equal, want %v, got %v", want == tC.rpc, switched)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// simnode serves simulated Ethereum JSON-RPC node for local development without network access
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/simnode"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+var (
+	port        = flag.Int("port", 8545, "HTTP port")
+	chainID     = flag.Uint64("chain-id", 1, "Chain ID")
+	genesis     = flag.Int("genesis", 1000, "Number of the first block")
+	blockTime   = flag.Duration("block-time", 2*time.Second, "Interval between mined blocks")
+	txsPerBlock = flag.Int("txs", 10, "Max number of transactions per block")
+	latency     = flag.Duration("latency", 0, "Delay of every response")
+	rateLimit   = flag.Int("rate-limit", 0, "Max requests per second, zero is unlimited")
+)
+
+// addresses sending transactions to each other, the first one is used in README examples
+var addresses = []types.Address{
+	types.MustParseAddress("0xe93685f3bba03016f02bd1828badd6195988d950"),
+	types.MustParseAddress("0x22a7a914cf352f7361c199188a23da94fe71b277"),
+	types.MustParseAddress("0xfe556e4f848c82093d0a33cc41761d18f67099ca"),
+	types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"),
+}
+
+func main() {
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		syscall.SIGTERM,
+		syscall.SIGINT,
+	)
+	defer cancel()
+
+	node := simnode.New(*chainID, *genesis)
+	node.SetLatency(*latency)
+	node.SetRateLimit(*rateLimit)
+
+	go mine(ctx, node)
+
+	server := http.Server{
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: node,
+	}
+
+	go func() {
+		slog.Info("Starting simulated node", "port", *port, "chain_id", *chainID, "genesis", *genesis)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Failed to start http server", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		slog.Warn("Failed to shutdown http server", "error", err)
+	}
+
+	slog.Info("Simulated node stopped")
+}
+
+// mine appends blocks with random transactions between known addresses
+func mine(ctx context.Context, node *simnode.Node) {
+	ticker := time.NewTicker(*blockTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			txs := make([]simnode.Transaction, rand.IntN(*txsPerBlock+1))
+			for i := range txs {
+				txs[i] = simnode.Transaction{
+					From:   addresses[rand.IntN(len(addresses))],
+					To:     addresses[rand.IntN(len(addresses))],
+					Failed: rand.IntN(10) == 0,
+				}
+			}
+
+			b := node.Mine(txs...)
+			slog.Info("Mined block", "number", b.Number, "tx_count", len(b.Transactions))
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/repository/file"
+	"github.com/avelex/blockchain-parser/internal/snapshot"
+)
+
+// takeSnapshot writes snapshot of local store, snapshot of running instance is served by /v1/snapshot
+func takeSnapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	output := fs.String("output", "", "Output file, stdout if empty")
+	fs.Parse(args)
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	repo, err := openLocalStore(cfg.Storage)
+	if err != nil {
+		return err
+	}
+
+	chainIDs, err := configuredChainIDs(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	s, err := snapshot.Take(ctx, repo, chainIDs)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output: %w", err)
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := json.NewEncoder(bw).Encode(s); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return bw.Flush()
+}
+
+// restore seeds local store from snapshot before instance is started,
+// chain IDs and block continuity are validated before anything is written
+func restore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	input := fs.String("file", "", "Snapshot file taken by snapshot command or /v1/snapshot")
+	fs.Parse(args)
+
+	if *input == "" {
+		return errors.New("--file is required")
+	}
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Storage.Path == "" {
+		return errors.New("storage.path is not set, restore into running instance with /v1/snapshot/restore")
+	}
+
+	f, err := os.Open(*input)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	var s snapshot.Snapshot
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(&s); err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	chainIDs, err := configuredChainIDs(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Validate(chainIDs); err != nil {
+		return err
+	}
+
+	repo, err := file.Open(cfg.Storage.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer repo.Close()
+
+	if err := snapshot.Restore(ctx, repo, s); err != nil {
+		return err
+	}
+
+	for _, c := range s.Chains {
+		slog.Info("Restored chain", "chain_id", c.ChainID, "checkpoint", c.Checkpoint, "subscriptions", len(c.Subscriptions))
+
+		if gaps := c.Gaps(); len(gaps) > 0 {
+			slog.Warn("Restored chain has gaps, fill them with backfill", "chain_id", c.ChainID, "gaps", gaps)
+		}
+	}
+
+	return nil
+}
+
+// configuredChainIDs returns IDs of all configured chains, RPC is called only for chain without configured ID
+func configuredChainIDs(ctx context.Context, cfg config.Config) ([]uint64, error) {
+	chains := cfg.ChainConfigs()
+
+	ids := make([]uint64, 0, len(chains))
+	for _, c := range chains {
+		if c.ChainID != 0 {
+			ids = append(ids, c.ChainID)
+			continue
+		}
+
+		// only the single chain may be configured without ID
+		id, err := localChainID(ctx, cfg, 0)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const statusTimeout = 10 * time.Second
+
+// status prints sync status of all chains of running instance
+func status(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	conf := bindConfigFlags(fs)
+	url := fs.String("url", "", "API url of running instance, http://localhost with configured port if empty")
+	apiKey := fs.String("api-key", "", "API key if auth is enabled")
+	fs.Parse(args)
+
+	cfg, err := conf.load()
+	if err != nil {
+		return err
+	}
+
+	base := *url
+	if base == "" {
+		base = fmt.Sprintf("http://localhost:%d", cfg.Port)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(base, "/")+"/v1/chains", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if *apiKey != "" {
+		req.Header.Set("X-API-Key", *apiKey)
+	}
+
+	client := &http.Client{Timeout: statusTimeout}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", base, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return fmt.Errorf("failed to format response: %w", err)
+	}
+
+	out.WriteByte('\n')
+	_, err = out.WriteTo(os.Stdout)
+
+	return err
+}
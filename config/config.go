@@ -1,30 +1,280 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
 	"os"
+	"slices"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Port           int           `yaml:"port"`
+	Port int `yaml:"port"`
+	// debug, info, warn or error
+	LogLevel string `yaml:"log_level"`
+	// single chain parameters, used when chains are not declared,
+	// blocks interval and confirmations are also defaults for declared chains
 	RPC            string        `yaml:"rpc"`
 	BlocksInterval time.Duration `yaml:"blocks_interval"`
 	StartBlock     int           `yaml:"start_block,omitempty"`
+	// number of blocks on top of a block to consider its transactions confirmed
+	Confirmations int `yaml:"confirmations,omitempty"`
+	// chains parsed by the process, the first one is the default chain of API
+	Chains    []ChainConfig   `yaml:"chains,omitempty"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	Webhook   WebhookConfig   `yaml:"webhook"`
+	Readiness ReadinessConfig `yaml:"readiness"`
+	Auth      AuthConfig      `yaml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	API       APIConfig       `yaml:"api"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Retention RetentionConfig `yaml:"retention"`
 }
 
-func LoadConfig(path string) (Config, error) {
-	bytes, err := os.ReadFile(path)
-	if err != nil {
-		return Config{}, fmt.Errorf("failed to read config file: %w", err)
+type ChainConfig struct {
+	// used in logs, chain ID is used if empty
+	Name string `yaml:"name"`
+	// validated against eth_chainId at startup, taken from RPC if zero
+	ChainID        uint64        `yaml:"chain_id"`
+	RPC            string        `yaml:"rpc"`
+	BlocksInterval time.Duration `yaml:"blocks_interval"`
+	StartBlock     int           `yaml:"start_block,omitempty"`
+	Confirmations  int           `yaml:"confirmations,omitempty"`
+}
+
+type WebSocketConfig struct {
+	// number of messages buffered per connection before it's considered a slow consumer
+	SendBuffer   int           `yaml:"send_buffer"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+}
+
+type WebhookConfig struct {
+	// key for HMAC-SHA256 signature of delivered payloads
+	Secret string `yaml:"secret"`
+	// file with subscriptions and undelivered events, kept in memory only if empty
+	OutboxPath   string        `yaml:"outbox_path"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+type ReadinessConfig struct {
+	// parser is not ready when it's behind chain head by more blocks
+	MaxLagBlocks int `yaml:"max_lag_blocks"`
+	// parser is not ready when the last successful RPC call is older
+	MaxRPCAge time.Duration `yaml:"max_rpc_age"`
+}
+
+type AuthConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// bootstrap admin key used to create other keys
+	AdminKey string `yaml:"admin_key"`
+	// file with hashed keys and their subscriptions, kept in memory only if empty
+	KeysPath string `yaml:"keys_path"`
+}
+
+type RateLimitConfig struct {
+	PerIP  LimitConfig `yaml:"per_ip"`
+	PerKey LimitConfig `yaml:"per_key"`
+	// zero means unlimited
+	MaxSubscriptionsPerKey int `yaml:"max_subscriptions_per_key"`
+	// number of addresses subscribed by all clients when auth is disabled, zero means unlimited
+	MaxSubscriptions int `yaml:"max_subscriptions"`
+}
+
+type LimitConfig struct {
+	// requests per second, zero disables limit
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+type APIConfig struct {
+	// max number of addresses in bulk requests
+	MaxBulkAddresses int   `yaml:"max_bulk_addresses"`
+	MaxBodyBytes     int64 `yaml:"max_body_bytes"`
+	// body of snapshot restore request, snapshot contains all transactions so it's limited separately
+	MaxSnapshotBytes int64 `yaml:"max_snapshot_bytes"`
+}
+
+type StorageConfig struct {
+	// append-only log of transactions and processed blocks, kept in memory only if empty
+	Path string `yaml:"path"`
+}
+
+// RetentionConfig limits transactions kept in repository, zero limit is disabled
+type RetentionConfig struct {
+	// transactions with older block timestamp are evicted
+	MaxAge time.Duration `yaml:"max_age"`
+	// number of the latest transactions kept per address
+	MaxTransactions int `yaml:"max_transactions"`
+	// estimated size of all transactions, the oldest ones are evicted first
+	MaxBytes int64 `yaml:"max_bytes"`
+	// how often limits are enforced
+	Interval time.Duration `yaml:"interval"`
+	// limits of specific addresses, zero limit is taken from global one
+	Addresses []AddressRetentionConfig `yaml:"addresses,omitempty"`
+}
+
+type AddressRetentionConfig struct {
+	Address         string        `yaml:"address"`
+	MaxAge          time.Duration `yaml:"max_age"`
+	MaxTransactions int           `yaml:"max_transactions"`
+}
+
+// ChainConfigs returns declared chains with defaults from top level parameters,
+// or a single chain made of top level parameters if no chains declared
+func (c Config) ChainConfigs() []ChainConfig {
+	if len(c.Chains) == 0 {
+		return []ChainConfig{{
+			RPC:            c.RPC,
+			BlocksInterval: c.BlocksInterval,
+			StartBlock:     c.StartBlock,
+			Confirmations:  c.Confirmations,
+		}}
+	}
+
+	chains := make([]ChainConfig, len(c.Chains))
+	for i, chain := range c.Chains {
+		if chain.BlocksInterval == 0 {
+			chain.BlocksInterval = c.BlocksInterval
+		}
+		if chain.Confirmations == 0 {
+			chain.Confirmations = c.Confirmations
+		}
+		chains[i] = chain
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(bytes, &config); err != nil {
-		return Config{}, fmt.Errorf("failed to unmarshal config: %w", err)
+	return chains
+}
+
+// SetChainRPC sets RPC of chain with index i in ChainConfigs
+func (c *Config) SetChainRPC(i int, rpc string) {
+	if len(c.Chains) == 0 {
+		c.RPC = rpc
+		return
+	}
+	c.Chains[i].RPC = rpc
+}
+
+// Level returns parsed log level, info if it's invalid
+func (c Config) Level() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Default returns config used as the first layer, values missing in YAML, environment and flags stay default
+func Default() Config {
+	return Config{
+		Port:           8080,
+		LogLevel:       "info",
+		BlocksInterval: 10 * time.Second,
+		WebSocket: WebSocketConfig{
+			SendBuffer:   64,
+			WriteTimeout: 10 * time.Second,
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts:  8,
+			RetryBackoff: 5 * time.Second,
+			Timeout:      10 * time.Second,
+		},
+		Readiness: ReadinessConfig{
+			MaxLagBlocks: 50,
+			MaxRPCAge:    5 * time.Minute,
+		},
+		API: APIConfig{
+			MaxBulkAddresses: 10000,
+			MaxBodyBytes:     4 << 20,
+			MaxSnapshotBytes: 256 << 20,
+		},
+		Retention: RetentionConfig{
+			Interval: time.Minute,
+		},
+	}
+}
+
+// Load builds config from layers, each overriding the previous one:
+// defaults, YAML file, environment variables and command line flags, and validates the result.
+// File is skipped if path is empty, lookupEnv and flags may be nil.
+func Load(path string, lookupEnv func(string) (string, bool), flags *Flags) (Config, error) {
+	config := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		// unknown keys are usually typos, silently ignoring them leaves defaults
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return Config{}, fmt.Errorf("failed to unmarshal config: %w", err)
+		}
+	}
+
+	if lookupEnv != nil {
+		if err := config.applyEnv(lookupEnv); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if flags != nil {
+		if err := flags.apply(&config); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config:\n%w", err)
 	}
 
 	return config, nil
 }
+
+// Redacted returns copy of config safe to log, secrets are replaced
+// and RPC urls keep only scheme and host, as path and query usually contain provider API key
+func (c Config) Redacted() Config {
+	c.RPC = redactURL(c.RPC)
+
+	c.Chains = slices.Clone(c.Chains)
+	for i := range c.Chains {
+		c.Chains[i].RPC = redactURL(c.Chains[i].RPC)
+	}
+
+	c.Webhook.Secret = redact(c.Webhook.Secret)
+	c.Auth.AdminKey = redact(c.Auth.AdminKey)
+
+	return c
+}
+
+const redacted = "REDACTED"
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redact(raw)
+	}
+
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" {
+		return raw
+	}
+
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts names of environment variables overriding config values,
+// name is made of yaml keys path, e.g. PARSER_RPC, PARSER_WEBHOOK_SECRET or PARSER_CHAINS_0_RPC
+const EnvPrefix = "PARSER_"
+
+// Flags keeps config values set by command line flags until config is loaded
+type Flags struct {
+	values map[string]string
+}
+
+// BindFlags defines flag for every config value on fs, named by yaml keys path, e.g. -rpc or -rate-limit-per-ip-rate.
+// Declared chains are configured in YAML or environment only.
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{values: make(map[string]string)}
+
+	defaults := Default()
+	for _, fld := range fields(&defaults) {
+		name := fld.flagName()
+		usage := "overrides " + strings.Join(fld.path, ".")
+
+		set := func(s string) error {
+			// parsing into defaults copy only checks value type
+			if err := fld.set(s); err != nil {
+				return err
+			}
+
+			f.values[name] = s
+			return nil
+		}
+
+		if fld.value.Kind() == reflect.Bool {
+			fs.BoolFunc(name, usage, set)
+		} else {
+			fs.Func(name, usage, set)
+		}
+	}
+
+	return f
+}
+
+func (f *Flags) apply(c *Config) error {
+	for _, fld := range fields(c) {
+		s, ok := f.values[fld.flagName()]
+		if !ok {
+			continue
+		}
+
+		if err := fld.set(s); err != nil {
+			return fmt.Errorf("failed to parse flag -%s: %w", fld.flagName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	for _, fld := range fields(c) {
+		s, ok := lookupEnv(fld.envName())
+		if !ok {
+			continue
+		}
+
+		if err := fld.set(s); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", fld.envName(), err)
+		}
+	}
+
+	return nil
+}
+
+// field is a config value addressed by yaml keys path, e.g. [rate_limit per_ip rate]
+type field struct {
+	path  []string
+	value reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fields returns settable config values, elements of declared chains are addressed by index
+func fields(c *Config) []field {
+	return appendFields(nil, nil, reflect.ValueOf(c).Elem())
+}
+
+func appendFields(dst []field, path []string, v reflect.Value) []field {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		fieldPath := slices.Concat(path, []string{name})
+		value := v.Field(i)
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			dst = appendFields(dst, fieldPath, value)
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < value.Len(); j++ {
+				dst = appendFields(dst, slices.Concat(fieldPath, []string{strconv.Itoa(j)}), value.Index(j))
+			}
+		default:
+			dst = append(dst, field{path: fieldPath, value: value})
+		}
+	}
+
+	return dst
+}
+
+func (f field) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.Join(f.path, "_"))
+}
+
+func (f field) flagName() string {
+	return strings.ReplaceAll(strings.Join(f.path, "-"), "_", "-")
+}
+
+func (f field) set(s string) error {
+	if f.value.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		f.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, f.value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(i)
+	case reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.value.SetUint(u)
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		f.value.SetFloat(v)
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// Validate checks every config value and reports all problems at once
+func (c Config) Validate() error {
+	var errs []error
+
+	check := func(ok bool, field, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.Port > 0 && c.Port <= 65535, "port", "must be between 1 and 65535, got %d", c.Port)
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.LogLevel)) == nil, "log_level", "must be debug, info, warn or error, got %q", c.LogLevel)
+
+	if len(c.Chains) == 0 {
+		errs = append(errs, validateRPC("rpc", c.RPC))
+	}
+	errs = append(errs, validateInterval("blocks_interval", c.BlocksInterval))
+	check(c.StartBlock >= 0, "start_block", "must not be negative, remove it to start from the latest block")
+	check(c.Confirmations >= 0, "confirmations", "must not be negative, got %d", c.Confirmations)
+
+	chainIDs := make(map[uint64]int)
+	names := make(map[string]int)
+
+	for i, chain := range c.Chains {
+		field := fmt.Sprintf("chains[%d]", i)
+
+		errs = append(errs, validateRPC(field+".rpc", chain.RPC))
+		if chain.BlocksInterval != 0 {
+			errs = append(errs, validateInterval(field+".blocks_interval", chain.BlocksInterval))
+		}
+		check(chain.StartBlock >= 0, field+".start_block", "must not be negative, remove it to start from the latest block")
+		check(chain.Confirmations >= 0, field+".confirmations", "must not be negative, got %d", chain.Confirmations)
+
+		if chain.ChainID != 0 {
+			prev, ok := chainIDs[chain.ChainID]
+			check(!ok, field+".chain_id", "%d is already declared by chains[%d]", chain.ChainID, prev)
+			chainIDs[chain.ChainID] = i
+		}
+
+		if chain.Name != "" {
+			prev, ok := names[chain.Name]
+			check(!ok, field+".name", "%q is already declared by chains[%d]", chain.Name, prev)
+			names[chain.Name] = i
+		}
+	}
+
+	check(c.WebSocket.SendBuffer > 0, "websocket.send_buffer", "must be positive, got %d", c.WebSocket.SendBuffer)
+	errs = append(errs, validateInterval("websocket.write_timeout", c.WebSocket.WriteTimeout))
+
+	check(c.Webhook.MaxAttempts > 0, "webhook.max_attempts", "must be positive, got %d", c.Webhook.MaxAttempts)
+	errs = append(errs, validateInterval("webhook.retry_backoff", c.Webhook.RetryBackoff))
+	errs = append(errs, validateInterval("webhook.timeout", c.Webhook.Timeout))
+
+	check(c.Readiness.MaxLagBlocks > 0, "readiness.max_lag_blocks", "must be positive, got %d", c.Readiness.MaxLagBlocks)
+	errs = append(errs, validateInterval("readiness.max_rpc_age", c.Readiness.MaxRPCAge))
+
+	check(!c.Auth.Enabled || c.Auth.AdminKey != "" || c.Auth.KeysPath != "", "auth.admin_key",
+		"is required when auth is enabled without keys_path, otherwise no key can be created")
+
+	limits := []struct {
+		field string
+		limit LimitConfig
+	}{
+		{"rate_limit.per_ip", c.RateLimit.PerIP},
+		{"rate_limit.per_key", c.RateLimit.PerKey},
+	}
+	for _, l := range limits {
+		check(l.limit.Rate >= 0, l.field+".rate", "must not be negative, set 0 to disable limit")
+		check(l.limit.Burst >= 0, l.field+".burst", "must not be negative, got %d", l.limit.Burst)
+	}
+	check(c.RateLimit.MaxSubscriptionsPerKey >= 0, "rate_limit.max_subscriptions_per_key", "must not be negative, set 0 for unlimited")
+	check(c.RateLimit.MaxSubscriptions >= 0, "rate_limit.max_subscriptions", "must not be negative, set 0 for unlimited")
+
+	check(c.API.MaxBulkAddresses > 0, "api.max_bulk_addresses", "must be positive, got %d", c.API.MaxBulkAddresses)
+	check(c.API.MaxBodyBytes > 0, "api.max_body_bytes", "must be positive, got %d", c.API.MaxBodyBytes)
+	check(c.API.MaxSnapshotBytes > 0, "api.max_snapshot_bytes", "must be positive, got %d", c.API.MaxSnapshotBytes)
+
+	check(c.Retention.MaxAge >= 0, "retention.max_age", "must not be negative, set 0 to keep transactions of any age")
+	check(c.Retention.MaxTransactions >= 0, "retention.max_transactions", "must not be negative, set 0 for unlimited")
+	check(c.Retention.MaxBytes >= 0, "retention.max_bytes", "must not be negative, set 0 for unlimited")
+	errs = append(errs, validateInterval("retention.interval", c.Retention.Interval))
+
+	addresses := make(map[types.Address]int)
+
+	for i, a := range c.Retention.Addresses {
+		field := fmt.Sprintf("retention.addresses[%d]", i)
+
+		address, err := types.ParseAddress(a.Address)
+		check(err == nil, field+".address", "invalid address %q", a.Address)
+
+		if err == nil {
+			prev, ok := addresses[address]
+			check(!ok, field+".address", "%s is already declared by retention.addresses[%d]", a.Address, prev)
+			addresses[address] = i
+		}
+
+		check(a.MaxAge >= 0, field+".max_age", "must not be negative, set 0 to use global max_age")
+		check(a.MaxTransactions >= 0, field+".max_transactions", "must not be negative, set 0 to use global max_transactions")
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateRPC(field, raw string) error {
+	if raw == "" {
+		return fmt.Errorf("%s: is required, e.g. https://1rpc.io/eth", field)
+	}
+
+	// parse error is not wrapped, it contains url with API key
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: invalid url", field)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s: must start with http:// or https://, e.g. https://1rpc.io/eth", field)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("%s: host is missing", field)
+	}
+
+	return nil
+}
+
+func validateInterval(field string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%s: must be positive, e.g. 10s", field)
+	}
+
+	// yaml number without unit is decoded as nanoseconds
+	if d < time.Millisecond {
+		return fmt.Errorf("%s: %s is too short, duration needs a unit, e.g. 10s", field, d)
+	}
+
+	return nil
+}
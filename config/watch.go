@@ -0,0 +1,200 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// liveFields are applied to running parser on reload, other changes require restart.
+// Index of declared chain is replaced by *.
+var liveFields = map[string]bool{
+	"log_level":                true,
+	"rpc":                      true,
+	"blocks_interval":          true,
+	"chains.*.rpc":             true,
+	"chains.*.blocks_interval": true,
+	"rate_limit.per_ip.rate":   true,
+	"rate_limit.per_ip.burst":  true,
+	"rate_limit.per_key.rate":  true,
+	"rate_limit.per_key.burst": true,
+}
+
+// Reload returns current config with live values taken from next one, paths of applied values as changed
+// and paths of other modified values as rejected
+func Reload(current, next Config) (applied Config, changed, rejected []string) {
+	applied = current
+
+	// lists are matched by index, so adding or removing an element changes the whole list
+	rejected = alignLists(nil, reflect.ValueOf(&applied).Elem(), reflect.ValueOf(&next).Elem())
+
+	nextFields := fields(&next)
+
+	for i, f := range fields(&applied) {
+		n := nextFields[i]
+		if f.value.Equal(n.value) {
+			continue
+		}
+
+		path := strings.Join(f.path, ".")
+
+		if !liveFields[pattern(f.path)] {
+			rejected = append(rejected, path)
+			continue
+		}
+
+		f.value.Set(n.value)
+		changed = append(changed, path)
+	}
+
+	return applied, changed, rejected
+}
+
+// alignLists copies lists of structs of applied, so applying values doesn't modify current config,
+// list of next with different length is replaced by applied one and its path is returned as rejected
+func alignLists(path []string, applied, next reflect.Value) (rejected []string) {
+	t := applied.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		fieldPath := slices.Concat(path, []string{name})
+		a, n := applied.Field(i), next.Field(i)
+
+		switch {
+		case a.Kind() == reflect.Struct:
+			rejected = append(rejected, alignLists(fieldPath, a, n)...)
+		case a.Kind() == reflect.Slice && a.Type().Elem().Kind() == reflect.Struct:
+			if !a.IsNil() {
+				clone := reflect.MakeSlice(a.Type(), a.Len(), a.Len())
+				reflect.Copy(clone, a)
+				a.Set(clone)
+			}
+
+			if a.Len() != n.Len() {
+				rejected = append(rejected, strings.Join(fieldPath, "."))
+				n.Set(a)
+				continue
+			}
+
+			for j := 0; j < a.Len(); j++ {
+				rejected = append(rejected, alignLists(slices.Concat(fieldPath, []string{strconv.Itoa(j)}), a.Index(j), n.Index(j))...)
+			}
+		}
+	}
+
+	return rejected
+}
+
+// pattern replaces chain index in path by *
+func pattern(path []string) string {
+	parts := slices.Clone(path)
+	for i, part := range parts {
+		if part != "" && strings.Trim(part, "0123456789") == "" {
+			parts[i] = "*"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// Watcher reloads config when file is modified or SIGHUP is received
+type Watcher struct {
+	path     string
+	interval time.Duration
+	load     func() (Config, error)
+	current  Config
+	updates  chan func(c *Config)
+}
+
+// NewWatcher creates watcher polling modification time of file every interval,
+// load builds config from all layers, current is the running config
+func NewWatcher(path string, interval time.Duration, current Config, load func() (Config, error)) *Watcher {
+	return &Watcher{
+		path:     path,
+		interval: interval,
+		load:     load,
+		current:  current,
+		updates:  make(chan func(c *Config)),
+	}
+}
+
+// Update modifies running config from outside of apply, e.g. when a change is applied asynchronously.
+// It blocks until watcher receives update or ctx is done.
+func (w *Watcher) Update(ctx context.Context, update func(c *Config)) {
+	select {
+	case <-ctx.Done():
+	case w.updates <- update:
+	}
+}
+
+// Start watches config until ctx is done, apply is called with previous and reloaded config on live changes
+// and returns config with changes it actually applied, the rest are applied again on the next reload
+func (w *Watcher) Start(ctx context.Context, apply func(prev, next Config) Config) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	modTime := w.modTime()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update := <-w.updates:
+			update(&w.current)
+		case <-hup:
+			slog.Info("SIGHUP received, reloading config", "path", w.path)
+			w.reload(apply)
+		case <-ticker.C:
+			t := w.modTime()
+			if t.Equal(modTime) {
+				continue
+			}
+
+			modTime = t
+			slog.Info("Config file modified, reloading config", "path", w.path)
+			w.reload(apply)
+		}
+	}
+}
+
+func (w *Watcher) reload(apply func(prev, next Config) Config) {
+	next, err := w.load()
+	if err != nil {
+		slog.Error("Failed to reload config, running config is kept", "error", err)
+		return
+	}
+
+	applied, changed, rejected := Reload(w.current, next)
+
+	if len(rejected) > 0 {
+		slog.Warn("Config changes require restart and are not applied", "fields", rejected)
+	}
+
+	if len(changed) == 0 {
+		slog.Info("No config changes to apply")
+		return
+	}
+
+	w.current = apply(w.current, applied)
+
+	slog.Info("Config reloaded", "changed", changed)
+}
+
+// modTime is zero if file is missing, e.g. during replacement by editor
+func (w *Watcher) modTime() time.Time {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,102 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/config"
+)
+
+func Test_Watcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu     sync.Mutex
+		loaded = config.Default()
+	)
+
+	// reload sets config returned by load and modifies file, so watcher picks it up on next poll
+	modTime := time.Now()
+	reload := func(modify func(c *config.Config)) {
+		mu.Lock()
+		modify(&loaded)
+		mu.Unlock()
+
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	load := func() (config.Config, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return loaded, nil
+	}
+
+	type call struct{ prev, next config.Config }
+	calls := make(chan call, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := config.NewWatcher(path, 10*time.Millisecond, config.Default(), load)
+	go watcher.Start(ctx, func(prev, next config.Config) config.Config {
+		calls <- call{prev: prev, next: next}
+		// rate limit is applied, log level is not
+		next.LogLevel = prev.LogLevel
+		return next
+	})
+
+	wait := func() call {
+		t.Helper()
+		select {
+		case c := <-calls:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatalf("config is not reloaded")
+			return call{}
+		}
+	}
+
+	// update is received by running loop, so modification time of file is already taken
+	watcher.Update(ctx, func(c *config.Config) {})
+
+	reload(func(c *config.Config) {
+		c.LogLevel = "debug"
+		c.RateLimit.PerIP.Rate = 5
+		c.Port = 9000
+	})
+
+	got := wait()
+	if got.next.LogLevel != "debug" || got.next.RateLimit.PerIP.Rate != 5 || got.next.Port != config.Default().Port {
+		t.Fatalf("reloaded config is not equal, want live changes only, got %+v", got.next)
+	}
+
+	// log level is not applied, so it's changed again on the next reload
+	reload(func(c *config.Config) { c.RateLimit.PerIP.Burst = 10 })
+
+	got = wait()
+	if got.prev.LogLevel != "info" || got.prev.RateLimit.PerIP.Rate != 5 {
+		t.Fatalf("running config is not equal to applied one, got %+v", got.prev)
+	}
+	if got.next.LogLevel != "debug" {
+		t.Fatalf("log level is not equal, want %q, got %q", "debug", got.next.LogLevel)
+	}
+
+	// asynchronously applied change is recorded in running config
+	watcher.Update(ctx, func(c *config.Config) { c.LogLevel = "debug" })
+	reload(func(c *config.Config) { c.LogLevel = "warn" })
+
+	got = wait()
+	if got.prev.LogLevel != "debug" {
+		t.Fatalf("log level is not equal, want %q, got %q", "debug", got.prev.LogLevel)
+	}
+}
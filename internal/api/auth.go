@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/types"
+	"github.com/avelex/blockchain-parser/internal/webhook"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// authenticated requires valid API key when authentication is enabled and applies rate limits
+func (h *Handler) authenticated(next http.HandlerFunc) http.Handler {
+	if !h.conf.Auth.Enabled {
+		return h.limitByIP(next)
+	}
+	return h.limitByIP(h.authenticate(h.limitByKey(next)))
+}
+
+func (h *Handler) admin(next http.HandlerFunc) http.Handler {
+	return h.limitByIP(h.authenticate(requireAdmin(next)))
+}
+
+// authenticate rejects requests without valid key passed in X-API-Key or Authorization: Bearer header
+func (h *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plaintext := requestKey(r)
+		if plaintext == "" {
+			renderError(w, newError(http.StatusUnauthorized, codeUnauthorized, "missing api key"))
+			return
+		}
+
+		key, ok := h.keys.Authenticate(plaintext)
+		if !ok {
+			renderError(w, newError(http.StatusUnauthorized, codeUnauthorized, "invalid api key"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), key)))
+	})
+}
+
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := auth.FromContext(r.Context())
+		if !ok || !key.Admin {
+			renderError(w, newError(http.StatusForbidden, codeForbidden, "admin key required"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	return ""
+}
+
+// subscriptionLimit returns number of addresses request key may subscribe, zero is unlimited.
+// Without auth all clients share the global limit under empty key ID, addresses are tracked only if it's set.
+func (h *Handler) subscriptionLimit(key auth.Key) (limit int, tracked bool) {
+	if !h.conf.Auth.Enabled {
+		return h.conf.RateLimit.MaxSubscriptions, h.conf.RateLimit.MaxSubscriptions > 0
+	}
+
+	if key.Admin {
+		return 0, true
+	}
+
+	return h.conf.RateLimit.MaxSubscriptionsPerKey, true
+}
+
+// owns reports whether the request key subscribed to address, admin keys own every address
+func (h *Handler) owns(r *http.Request, address types.Address) bool {
+	if !h.conf.Auth.Enabled {
+		return true
+	}
+
+	key, ok := auth.FromContext(r.Context())
+	if !ok {
+		return false
+	}
+
+	return key.Admin || h.keys.Owns(key.ID, address)
+}
+
+// ownsSubscription reports whether the request key created webhook subscription, admin keys own every subscription
+func (h *Handler) ownsSubscription(r *http.Request, sub webhook.Subscription) bool {
+	if !h.conf.Auth.Enabled {
+		return true
+	}
+
+	key, ok := auth.FromContext(r.Context())
+	if !ok {
+		return false
+	}
+
+	return key.Admin || sub.KeyID == key.ID
+}
+
+func (h *Handler) createKey(w http.ResponseWriter, r *http.Request) {
+	var req createKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		renderError(w, newError(http.StatusBadRequest, codeInvalidRequest, "name is required"))
+		return
+	}
+
+	key, plaintext, err := h.keys.Create(req.Name, req.Admin)
+	if err != nil {
+		slog.Error("failed to create api key", "error", err)
+		renderError(w, newError(http.StatusInternalServerError, codeInternal, "failed to create key"))
+		return
+	}
+
+	renderJSON(w, http.StatusCreated, createKeyResponse{Key: key, APIKey: plaintext})
+}
+
+func (h *Handler) listKeys(w http.ResponseWriter, r *http.Request) {
+	renderJSON(w, http.StatusOK, keysResponse{Keys: h.keys.List()})
+}
+
+func (h *Handler) revokeKey(w http.ResponseWriter, r *http.Request) {
+	revoked, err := h.keys.Revoke(r.PathValue("id"))
+	if err != nil {
+		slog.Error("failed to revoke api key", "error", err)
+		renderError(w, newError(http.StatusInternalServerError, codeInternal, "failed to revoke key"))
+		return
+	}
+
+	if !revoked {
+		renderError(w, newError(http.StatusNotFound, codeNotFound, "key not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,199 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/api"
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/types"
+	"github.com/avelex/blockchain-parser/internal/webhook"
+)
+
+const (
+	testChainID = 1
+	adminKey    = "admin-key"
+)
+
+var (
+	alice = types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	bob   = types.MustParseAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+)
+
+// testServer is API of a single chain parser which is never started
+type testServer struct {
+	mux        *http.ServeMux
+	keys       *auth.Store
+	dispatcher *webhook.Dispatcher
+	repo       *memory.Repository
+	parser     *parser.BlockchainParser
+	conf       config.Config
+}
+
+// newTestServer serves API with default config changed by configure, configure may be nil
+func newTestServer(t *testing.T, configure func(c *config.Config)) *testServer {
+	t.Helper()
+
+	conf := config.Default()
+	if configure != nil {
+		configure(&conf)
+	}
+
+	broker := events.NewBroker()
+	repo := memory.New()
+
+	p := parser.New(config.ChainConfig{ChainID: testChainID}, ethclient.New("http://127.0.0.1:0"), repo, broker)
+
+	dispatcher, err := webhook.New(conf.Webhook, broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewStore("", adminKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	api.NewHandler(parser.NewChains(p), broker, dispatcher, keys, repo, conf).Register(mux)
+
+	return &testServer{mux: mux, keys: keys, dispatcher: dispatcher, repo: repo, parser: p, conf: conf}
+}
+
+func enableAuth(c *config.Config) {
+	c.Auth.Enabled = true
+}
+
+// do serves request with API key, key is not sent if empty
+func (s *testServer) do(method, target, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set("X-API-Key", key)
+	}
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, r)
+
+	return rec
+}
+
+func Test_Auth(t *testing.T) {
+	s := newTestServer(t, enableAuth)
+
+	tenant, tenantKey, err := s.keys.Create("tenant", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, otherKey, err := s.keys.Create("other", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.keys.Grant(tenant.ID, alice, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// both keys own the address, webhook belongs only to its creator
+	if _, err := s.keys.Grant(other.ID, alice, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := s.dispatcher.Subscribe(alice, "http://example.com/hook", tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc   string
+		method string
+		target string
+		key    string
+		want   int
+	}{
+		{
+			desc:   "Missing key",
+			method: http.MethodGet,
+			target: "/v1/block",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			desc:   "Invalid key",
+			method: http.MethodGet,
+			target: "/v1/block",
+			key:    "invalid",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			desc:   "Valid key",
+			method: http.MethodGet,
+			target: "/v1/block",
+			key:    tenantKey,
+			want:   http.StatusOK,
+		},
+		{
+			desc:   "Admin route with non admin key",
+			method: http.MethodGet,
+			target: "/v1/admin/keys",
+			key:    tenantKey,
+			want:   http.StatusForbidden,
+		},
+		{
+			desc:   "Admin route with admin key",
+			method: http.MethodGet,
+			target: "/v1/admin/keys",
+			key:    adminKey,
+			want:   http.StatusOK,
+		},
+		{
+			desc:   "Transactions of owned address",
+			method: http.MethodGet,
+			target: "/v1/addresses/" + alice.Hex() + "/transactions",
+			key:    tenantKey,
+			want:   http.StatusOK,
+		},
+		{
+			desc:   "Transactions of address owned by another key",
+			method: http.MethodGet,
+			target: "/v1/addresses/" + bob.Hex() + "/transactions",
+			key:    tenantKey,
+			want:   http.StatusForbidden,
+		},
+		{
+			desc:   "Deliveries of own webhook",
+			method: http.MethodGet,
+			target: "/v1/subscriptions/" + sub.ID + "/deliveries",
+			key:    tenantKey,
+			want:   http.StatusOK,
+		},
+		{
+			desc:   "Deliveries of webhook created by another key of the same address",
+			method: http.MethodGet,
+			target: "/v1/subscriptions/" + sub.ID + "/deliveries",
+			key:    otherKey,
+			want:   http.StatusNotFound,
+		},
+		{
+			desc:   "Deliveries of any webhook with admin key",
+			method: http.MethodGet,
+			target: "/v1/subscriptions/" + sub.ID + "/deliveries",
+			key:    adminKey,
+			want:   http.StatusOK,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := s.do(tC.method, tC.target, tC.key, "")
+			if rec.Code != tC.want {
+				t.Fatalf("status is not equal, want %d, got %d: %s", tC.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
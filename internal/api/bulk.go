@@ -0,0 +1,258 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+type bulkSubscriptionResult struct {
+	// checksummed address, or address as passed if it's invalid
+	Address string    `json:"address"`
+	Created bool      `json:"created"`
+	Error   *apiError `json:"error,omitempty"`
+}
+
+type bulkSubscriptionResponse struct {
+	Subscribed int                      `json:"subscribed"`
+	Failed     int                      `json:"failed"`
+	Results    []bulkSubscriptionResult `json:"results"`
+}
+
+type transactionsQueryRequest struct {
+	Addresses []string `json:"addresses"`
+	// max number of the latest transactions per address, zero means all
+	Limit int `json:"limit,omitempty"`
+}
+
+type transactionsQueryResult struct {
+	// checksummed address, or address as passed if it's invalid
+	Address      string              `json:"address"`
+	Transactions []types.Transaction `json:"transactions"`
+	Error        *apiError           `json:"error,omitempty"`
+}
+
+// transactionsQueryResponse documents streamed response of transactions query
+type transactionsQueryResponse struct {
+	ChainID uint64                    `json:"chain_id"`
+	Results []transactionsQueryResult `json:"results"`
+}
+
+// createSubscriptions subscribes to many addresses passed as JSON array or newline-delimited text
+func (h *Handler) createSubscriptions(w http.ResponseWriter, r *http.Request) {
+	addresses, apiErr := h.readAddresses(w, r)
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	key, _ := auth.FromContext(r.Context())
+
+	resp := bulkSubscriptionResponse{
+		Results: make([]bulkSubscriptionResult, len(addresses)),
+	}
+
+	// valid addresses, to be granted to key with single write
+	var (
+		valid        []types.Address
+		validResults []int
+	)
+
+	seen := make(map[types.Address]struct{}, len(addresses))
+
+	for i, raw := range addresses {
+		result := &resp.Results[i]
+		result.Address = raw
+
+		address, apiErr := parseAddress(raw)
+		if apiErr != nil {
+			result.Error = apiErr
+			continue
+		}
+		result.Address = address.Checksum()
+
+		// duplicates in the same request
+		if _, ok := seen[address]; ok {
+			continue
+		}
+		seen[address] = struct{}{}
+
+		valid = append(valid, address)
+		validResults = append(validResults, i)
+	}
+
+	// addresses are granted within limit before they are subscribed, so refused addresses are never parsed,
+	// statuses are nil if addresses are not tracked
+	var statuses []auth.GrantStatus
+
+	if limit, tracked := h.subscriptionLimit(key); tracked && len(valid) > 0 {
+		var err error
+		statuses, err = h.keys.GrantAll(key.ID, valid, limit)
+		if err != nil {
+			slog.Error("failed to grant addresses", "key", key.ID, "count", len(valid), "error", err)
+			renderError(w, newError(http.StatusInternalServerError, codeInternal, "failed to subscribe"))
+			return
+		}
+	}
+
+	for i, address := range valid {
+		result := &resp.Results[validResults[i]]
+
+		if statuses != nil && statuses[i] == auth.QuotaExceeded {
+			result.Error = subscriptionLimitExceeded()
+			continue
+		}
+
+		result.Created = h.chains.Subscribe(address)
+
+		// with auth address is created for the key even if another key subscribed it
+		if h.conf.Auth.Enabled {
+			result.Created = statuses[i] == auth.Granted
+		}
+	}
+
+	for _, result := range resp.Results {
+		if result.Error != nil {
+			resp.Failed++
+		} else {
+			resp.Subscribed++
+		}
+	}
+
+	renderJSON(w, http.StatusOK, resp)
+}
+
+// queryTransactions streams transactions of many addresses, so large responses are not buffered
+func (h *Handler) queryTransactions(w http.ResponseWriter, r *http.Request) {
+	p, apiErr := h.chain(r)
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.conf.API.MaxBodyBytes)
+
+	var req transactionsQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		renderError(w, bodyError(err, "invalid json body"))
+		return
+	}
+
+	if len(req.Addresses) == 0 {
+		renderError(w, newError(http.StatusBadRequest, codeInvalidRequest, "addresses are required"))
+		return
+	}
+
+	if maxAddresses := h.conf.API.MaxBulkAddresses; len(req.Addresses) > maxAddresses {
+		renderError(w, newError(http.StatusRequestEntityTooLarge, codeInvalidRequest, fmt.Sprintf("too many addresses, max %d", maxAddresses)))
+		return
+	}
+
+	if req.Limit < 0 {
+		renderError(w, newError(http.StatusBadRequest, codeInvalidRequest, "limit must not be negative"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	fmt.Fprintf(w, `{"chain_id":%d,"results":[`, p.ChainID())
+
+	for i, raw := range req.Addresses {
+		if i > 0 {
+			io.WriteString(w, ",")
+		}
+
+		result := h.queryAddress(r, p, raw, req.Limit)
+
+		if err := enc.Encode(result); err != nil {
+			// client is gone, response can't be fixed anyway
+			slog.Debug("failed to stream transactions", "error", err)
+			return
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	io.WriteString(w, "]}\n")
+}
+
+// queryAddress returns up to limit latest transactions of address, limit zero means all
+func (h *Handler) queryAddress(r *http.Request, p parser.Parser, raw string, limit int) transactionsQueryResult {
+	address, apiErr := parseAddress(raw)
+	if apiErr != nil {
+		return transactionsQueryResult{Address: raw, Error: apiErr}
+	}
+
+	result := transactionsQueryResult{Address: address.Checksum()}
+
+	transactions, apiErr := h.transactions(r, p, address)
+	if apiErr != nil {
+		result.Error = apiErr
+		return result
+	}
+
+	if limit > 0 && len(transactions) > limit {
+		transactions = transactions[len(transactions)-limit:]
+	}
+	result.Transactions = transactions
+
+	return result
+}
+
+// readAddresses reads JSON array of addresses or newline-delimited addresses from request body
+func (h *Handler) readAddresses(w http.ResponseWriter, r *http.Request) ([]string, *apiError) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.conf.API.MaxBodyBytes))
+	if err != nil {
+		return nil, bodyError(err, "failed to read body")
+	}
+
+	var addresses []string
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &addresses); err != nil {
+			return nil, newError(http.StatusBadRequest, codeInvalidRequest, "invalid json array of addresses")
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				addresses = append(addresses, line)
+			}
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, newError(http.StatusBadRequest, codeInvalidRequest, "addresses are required")
+	}
+
+	if maxAddresses := h.conf.API.MaxBulkAddresses; len(addresses) > maxAddresses {
+		return nil, newError(http.StatusRequestEntityTooLarge, codeInvalidRequest, fmt.Sprintf("too many addresses, max %d", maxAddresses))
+	}
+
+	return addresses, nil
+}
+
+// bodyError is 413 if body read through http.MaxBytesReader exceeds limit, otherwise 400 with message
+func bodyError(err error, message string) *apiError {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return newError(http.StatusRequestEntityTooLarge, codeInvalidRequest, fmt.Sprintf("body exceeds %d bytes", maxBytesErr.Limit))
+	}
+	return newError(http.StatusBadRequest, codeInvalidRequest, message)
+}
@@ -0,0 +1,234 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+type bulkResult struct {
+	Address string `json:"address"`
+	Created bool   `json:"created"`
+	Error   *struct {
+		Code string `json:"code"`
+	} `json:"error"`
+}
+
+// code returns error code of result, empty if it succeeded
+func (r bulkResult) code() string {
+	if r.Error == nil {
+		return ""
+	}
+	return r.Error.Code
+}
+
+func Test_CreateSubscriptions(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		configure func(c *config.Config)
+		body      string
+		wantCode  int
+		// error codes of results, empty if address is subscribed
+		want []string
+		// number of addresses subscribed by parser
+		wantSubscribers int
+	}{
+		{
+			desc:            "JSON array",
+			body:            fmt.Sprintf(`[%q,%q]`, alice, bob),
+			wantCode:        http.StatusOK,
+			want:            []string{"", ""},
+			wantSubscribers: 2,
+		},
+		{
+			desc:            "Newline-delimited text with invalid address and duplicate",
+			body:            fmt.Sprintf("%s\n\n0x123\n%s\n", alice, "0x"+strings.ToUpper(alice.Hex()[2:])),
+			wantCode:        http.StatusOK,
+			want:            []string{"", "invalid_address", ""},
+			wantSubscribers: 1,
+		},
+		{
+			desc:            "Addresses over limit are not subscribed",
+			configure:       func(c *config.Config) { c.RateLimit.MaxSubscriptions = 1 },
+			body:            fmt.Sprintf(`[%q,%q,%q]`, alice, bob, carol),
+			wantCode:        http.StatusOK,
+			want:            []string{"", "subscription_limit_exceeded", "subscription_limit_exceeded"},
+			wantSubscribers: 1,
+		},
+		{
+			desc:      "Too many addresses",
+			configure: func(c *config.Config) { c.API.MaxBulkAddresses = 1 },
+			body:      fmt.Sprintf(`[%q,%q]`, alice, bob),
+			wantCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:      "Body too large",
+			configure: func(c *config.Config) { c.API.MaxBodyBytes = 64 },
+			body:      fmt.Sprintf(`[%q,%q]`, alice, bob),
+			wantCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:     "Empty body",
+			body:     "\n",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := newTestServer(t, tC.configure)
+
+			rec := s.do(http.MethodPost, "/v1/subscriptions/bulk", "", tC.body)
+			if rec.Code != tC.wantCode {
+				t.Fatalf("status is not equal, want %d, got %d: %s", tC.wantCode, rec.Code, rec.Body)
+			}
+
+			if got := s.parser.Status().Subscribers; got != tC.wantSubscribers {
+				t.Fatalf("subscribers are not equal, want %d, got %d", tC.wantSubscribers, got)
+			}
+
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Results []bulkResult `json:"results"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(resp.Results) != len(tC.want) {
+				t.Fatalf("results count is not equal, want %d, got %d", len(tC.want), len(resp.Results))
+			}
+
+			for i, result := range resp.Results {
+				if result.code() != tC.want[i] {
+					t.Fatalf("error of result %d is not equal, want %q, got %q", i, tC.want[i], result.code())
+				}
+			}
+		})
+	}
+}
+
+func Test_QueryTransactions(t *testing.T) {
+	tx1 := types.NewTransaction(testChainID, "0x01", alice, bob, 1)
+	tx2 := types.NewTransaction(testChainID, "0x02", bob, alice, 2)
+
+	testCases := []struct {
+		desc      string
+		configure func(c *config.Config)
+		body      string
+		wantCode  int
+		// hashes of transactions by result, nil if result is an error
+		want [][]string
+	}{
+		{
+			desc:     "All transactions",
+			body:     fmt.Sprintf(`{"addresses":[%q,%q]}`, alice, carol),
+			wantCode: http.StatusOK,
+			want:     [][]string{{"0x01", "0x02"}, {}},
+		},
+		{
+			desc:     "Latest transactions with limit",
+			body:     fmt.Sprintf(`{"addresses":[%q],"limit":1}`, alice),
+			wantCode: http.StatusOK,
+			want:     [][]string{{"0x02"}},
+		},
+		{
+			desc:     "Invalid address",
+			body:     `{"addresses":["0x123"]}`,
+			wantCode: http.StatusOK,
+			want:     [][]string{nil},
+		},
+		{
+			desc:      "Address is not owned by key",
+			configure: enableAuth,
+			body:      fmt.Sprintf(`{"addresses":[%q]}`, alice),
+			wantCode:  http.StatusOK,
+			want:      [][]string{nil},
+		},
+		{
+			desc:     "Negative limit",
+			body:     fmt.Sprintf(`{"addresses":[%q],"limit":-1}`, alice),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			desc:      "Too many addresses",
+			configure: func(c *config.Config) { c.API.MaxBulkAddresses = 1 },
+			body:      fmt.Sprintf(`{"addresses":[%q,%q]}`, alice, bob),
+			wantCode:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:      "Body too large",
+			configure: func(c *config.Config) { c.API.MaxBodyBytes = 32 },
+			body:      fmt.Sprintf(`{"addresses":[%q,%q]}`, alice, bob),
+			wantCode:  http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := newTestServer(t, tC.configure)
+
+			if err := s.repo.SaveTransactions(context.Background(), testChainID, alice, []types.Transaction{tx1, tx2}); err != nil {
+				t.Fatal(err)
+			}
+
+			key := ""
+			if s.conf.Auth.Enabled {
+				_, plaintext, err := s.keys.Create("tenant", false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				key = plaintext
+			}
+
+			rec := s.do(http.MethodPost, "/v1/transactions/query", key, tC.body)
+			if rec.Code != tC.wantCode {
+				t.Fatalf("status is not equal, want %d, got %d: %s", tC.wantCode, rec.Code, rec.Body)
+			}
+
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				ChainID uint64 `json:"chain_id"`
+				Results []struct {
+					Transactions []types.Transaction `json:"transactions"`
+					Error        *struct{}           `json:"error"`
+				} `json:"results"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode streamed response: %v", err)
+			}
+
+			if resp.ChainID != testChainID || len(resp.Results) != len(tC.want) {
+				t.Fatalf("response is not equal, want chain %d with %d results, got %+v", testChainID, len(tC.want), resp)
+			}
+
+			for i, result := range resp.Results {
+				if tC.want[i] == nil {
+					if result.Error == nil {
+						t.Fatalf("result %d must be an error", i)
+					}
+					continue
+				}
+
+				hashes := make([]string, 0, len(result.Transactions))
+				for _, tx := range result.Transactions {
+					hashes = append(hashes, tx.Hash)
+				}
+
+				if fmt.Sprint(hashes) != fmt.Sprint(tC.want[i]) {
+					t.Fatalf("transactions of result %d are not equal, want %v, got %v", i, tC.want[i], hashes)
+				}
+			}
+		})
+	}
+}
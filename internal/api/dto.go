@@ -0,0 +1,56 @@
+package api
+
+import (
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/types"
+	"github.com/avelex/blockchain-parser/internal/webhook"
+)
+
+type blockResponse struct {
+	ChainID uint64 `json:"chain_id"`
+	Number  int    `json:"number"`
+}
+
+type chainsResponse struct {
+	Chains []parser.Status `json:"chains"`
+}
+
+type subscriptionRequest struct {
+	Address string `json:"address"`
+	// optional URL for webhook delivery
+	Callback string `json:"callback,omitempty"`
+}
+
+type subscriptionResponse struct {
+	Address types.Address `json:"address"`
+	// false if address was already subscribed
+	Created bool                  `json:"created"`
+	Webhook *webhook.Subscription `json:"webhook,omitempty"`
+}
+
+type transactionsResponse struct {
+	ChainID      uint64              `json:"chain_id"`
+	Address      types.Address       `json:"address"`
+	Transactions []types.Transaction `json:"transactions"`
+}
+
+type deliveriesResponse struct {
+	SubscriptionID string             `json:"subscription_id"`
+	Deliveries     []webhook.Delivery `json:"deliveries"`
+}
+
+type createKeyRequest struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+}
+
+type createKeyResponse struct {
+	auth.Key
+	// plaintext key, shown only once
+	APIKey string `json:"key"`
+}
+
+type keysResponse struct {
+	Keys []auth.Key `json:"keys"`
+}
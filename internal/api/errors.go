@@ -0,0 +1,68 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// error codes of the error envelope
+const (
+	codeInvalidRequest    = "invalid_request"
+	codeInvalidAddress    = "invalid_address"
+	codeInvalidCallback   = "invalid_callback"
+	codeUnauthorized      = "unauthorized"
+	codeForbidden         = "forbidden"
+	codeUnknownChain      = "unknown_chain"
+	codeNotFound          = "not_found"
+	codeConflict          = "conflict"
+	codeRateLimited       = "rate_limited"
+	codeSubscriptionLimit = "subscription_limit_exceeded"
+	codeInternal          = "internal_error"
+)
+
+type apiError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// seconds before the next request may be allowed
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+type errorResponse struct {
+	Error *apiError `json:"error"`
+}
+
+func newError(status int, code, message string) *apiError {
+	return &apiError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// renderError writes error in {"error":{"code","message"}} envelope
+func renderError(w http.ResponseWriter, err *apiError) {
+	if err.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(err.RetryAfter))
+	}
+
+	renderJSON(w, err.Status, errorResponse{Error: err})
+}
+
+// quotaRetryAfter is suggested to clients exceeded subscriptions limit, limit isn't refilled over time,
+// so it only keeps clients from retrying in a loop
+const quotaRetryAfter = time.Minute
+
+func subscriptionLimitExceeded() *apiError {
+	err := newError(http.StatusTooManyRequests, codeSubscriptionLimit, "subscriptions limit exceeded")
+	err.RetryAfter = int(quotaRetryAfter.Seconds())
+	return err
+}
+
+func tooManyRequests(message string, retryAfter time.Duration) *apiError {
+	err := newError(http.StatusTooManyRequests, codeRateLimited, message)
+	err.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+	return err
+}
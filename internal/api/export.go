@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// rows written between flushes of exported response
+const exportFlushRows = 1000
+
+// exportTransactions streams transactions of subscribed address as CSV, JSON lines or Parquet file
+func (h *Handler) exportTransactions(w http.ResponseWriter, r *http.Request) {
+	p, apiErr := h.chain(r)
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	address, apiErr := parseAddress(r.URL.Query().Get("address"))
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	format := repository.FormatJSONL
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		f, err := repository.ParseFormat(raw)
+		if err != nil {
+			renderError(w, newError(http.StatusBadRequest, codeInvalidRequest, err.Error()))
+			return
+		}
+		format = f
+	}
+
+	transactions, apiErr := h.transactions(r, p, address)
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%d_%s.%s"`, p.ChainID(), address.Checksum(), format))
+
+	// status is already sent, so failed export is only logged
+	if err := writeTransactions(w, format, transactions); err != nil {
+		slog.Warn("failed to export transactions", "address", address, "format", format, "error", err)
+	}
+}
+
+// writeTransactions encodes transactions row by row and flushes response every exportFlushRows,
+// so client receives rows while export is in progress
+func writeTransactions(w http.ResponseWriter, format repository.Format, transactions []types.Transaction) error {
+	tw, err := repository.NewTransactionWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	rc := http.NewResponseController(w)
+
+	for i, tx := range transactions {
+		if err := tw.Write(tx); err != nil {
+			return err
+		}
+
+		if (i+1)%exportFlushRows == 0 {
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return fmt.Errorf("failed to flush response: %w", err)
+			}
+		}
+	}
+
+	return tw.Close()
+}
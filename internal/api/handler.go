@@ -2,63 +2,253 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
-	"regexp"
+	"net/url"
+	"strconv"
 
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/events"
 	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/ratelimit"
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/types"
+	"github.com/avelex/blockchain-parser/internal/webhook"
 )
 
-var ethAddressRegex = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
-
 type Handler struct {
-	parser parser.Parser
+	chains     *parser.Chains
+	broker     *events.Broker
+	dispatcher *webhook.Dispatcher
+	keys       *auth.Store
+	repo       repository.Repository
+	conf       config.Config
+
+	ipLimiter  *ratelimit.Limiter
+	keyLimiter *ratelimit.Limiter
 }
 
-func NewHandler(parser parser.Parser) *Handler {
+func NewHandler(chains *parser.Chains, broker *events.Broker, dispatcher *webhook.Dispatcher, keys *auth.Store, repo repository.Repository, conf config.Config) *Handler {
 	return &Handler{
-		parser: parser,
+		chains:     chains,
+		broker:     broker,
+		dispatcher: dispatcher,
+		keys:       keys,
+		repo:       repo,
+		conf:       conf,
+		ipLimiter:  ratelimit.New(conf.RateLimit.PerIP.Rate, conf.RateLimit.PerIP.Burst),
+		keyLimiter: ratelimit.New(conf.RateLimit.PerKey.Rate, conf.RateLimit.PerKey.Burst),
 	}
 }
 
-func (h *Handler) Register(m *http.ServeMux) {
-	m.HandleFunc("GET /block", h.showCurrentBlock)
-	m.HandleFunc("GET /subscribe", h.subscribeForTransactions)
-	m.HandleFunc("GET /transactions", h.showTransactions)
+// SetRateLimits applies reloaded rate limits, subscriptions limit requires restart
+func (h *Handler) SetRateLimits(conf config.RateLimitConfig) {
+	h.ipLimiter.SetLimit(conf.PerIP.Rate, conf.PerIP.Burst)
+	h.keyLimiter.SetLimit(conf.PerKey.Rate, conf.PerKey.Burst)
 }
 
-func (h *Handler) showCurrentBlock(w http.ResponseWriter, r *http.Request) {
-	block := h.parser.GetCurrentBlock()
-	renderJSON(w, http.StatusOK, block)
+func (h *Handler) getBlock(w http.ResponseWriter, r *http.Request) {
+	p, apiErr := h.chain(r)
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	renderJSON(w, http.StatusOK, blockResponse{ChainID: p.ChainID(), Number: p.GetCurrentBlock()})
 }
 
-func (h *Handler) subscribeForTransactions(w http.ResponseWriter, r *http.Request) {
-	address := r.URL.Query().Get("address")
-	if !ethAddressRegex.MatchString(address) {
-		renderJSON(w, http.StatusBadRequest, "invalid address")
+func (h *Handler) getStatus(w http.ResponseWriter, r *http.Request) {
+	p, apiErr := h.chain(r)
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	renderJSON(w, http.StatusOK, p.Status())
+}
+
+func (h *Handler) listChains(w http.ResponseWriter, r *http.Request) {
+	resp := chainsResponse{Chains: make([]parser.Status, 0, len(h.chains.All()))}
+	for _, p := range h.chains.All() {
+		resp.Chains = append(resp.Chains, p.Status())
+	}
+
+	renderJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) createSubscription(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.conf.API.MaxBodyBytes)
+
+	var req subscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		renderError(w, bodyError(err, "invalid json body"))
+		return
+	}
+
+	address, apiErr := parseAddress(req.Address)
+	if apiErr != nil {
+		renderError(w, apiErr)
 		return
 	}
 
-	if !h.parser.Subscribe(address) {
-		renderJSON(w, http.StatusOK, "already subscribed")
+	resp, apiErr := h.subscribe(r, address, req.Callback)
+	if apiErr != nil {
+		renderError(w, apiErr)
 		return
 	}
 
-	renderJSON(w, http.StatusOK, "subscribed")
+	status := http.StatusOK
+	if resp.Created {
+		status = http.StatusCreated
+	}
+
+	renderJSON(w, status, resp)
 }
 
-func (h *Handler) showTransactions(w http.ResponseWriter, r *http.Request) {
-	address := r.URL.Query().Get("address")
-	if !ethAddressRegex.MatchString(address) {
-		renderJSON(w, http.StatusBadRequest, "invalid address")
+func (h *Handler) listDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, apiErr := h.deliveries(r, r.PathValue("id"))
+	if apiErr != nil {
+		renderError(w, apiErr)
 		return
 	}
 
-	transactions := h.parser.GetTransactions(r.Context(), address)
+	renderJSON(w, http.StatusOK, deliveriesResponse{
+		SubscriptionID: r.PathValue("id"),
+		Deliveries:     deliveries,
+	})
+}
+
+func (h *Handler) listTransactions(w http.ResponseWriter, r *http.Request) {
+	p, apiErr := h.chain(r)
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	address, apiErr := parseAddress(r.PathValue("address"))
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	transactions, apiErr := h.transactions(r, p, address)
+	if apiErr != nil {
+		renderError(w, apiErr)
+		return
+	}
+
+	renderJSON(w, http.StatusOK, transactionsResponse{
+		ChainID:      p.ChainID(),
+		Address:      address,
+		Transactions: transactions,
+	})
+}
+
+// subscribe subscribes request key to address on all chains and registers webhook if callback is set
+func (h *Handler) subscribe(r *http.Request, address types.Address, callback string) (subscriptionResponse, *apiError) {
+	if callback != "" && !validCallback(callback) {
+		return subscriptionResponse{}, newError(http.StatusBadRequest, codeInvalidCallback, "invalid callback")
+	}
+
+	key, _ := auth.FromContext(r.Context())
+
+	resp := subscriptionResponse{Address: address}
+
+	// address is granted within limit before it's subscribed, so refused address is never parsed
+	if limit, tracked := h.subscriptionLimit(key); tracked {
+		granted, err := h.keys.Grant(key.ID, address, limit)
+		if errors.Is(err, auth.ErrQuotaExceeded) {
+			return subscriptionResponse{}, subscriptionLimitExceeded()
+		}
+		if err != nil {
+			slog.Error("failed to grant address", "key", key.ID, "address", address, "error", err)
+			return subscriptionResponse{}, newError(http.StatusInternalServerError, codeInternal, "failed to subscribe")
+		}
+
+		resp.Created = granted
+	}
+
+	// with auth address is created for the key even if another key subscribed it
+	if created := h.chains.Subscribe(address); !h.conf.Auth.Enabled {
+		resp.Created = created
+	}
+
+	if callback != "" {
+		sub, err := h.dispatcher.Subscribe(address, callback, key.ID)
+		if err != nil {
+			slog.Error("failed to subscribe webhook", "address", address, "error", err)
+			return subscriptionResponse{}, newError(http.StatusInternalServerError, codeInternal, "failed to subscribe webhook")
+		}
+
+		resp.Webhook = &sub
+	}
+
+	return resp, nil
+}
+
+func (h *Handler) deliveries(r *http.Request, subscriptionID string) ([]webhook.Delivery, *apiError) {
+	sub, ok := h.dispatcher.Subscription(subscriptionID)
+	if !ok || !h.ownsSubscription(r, sub) {
+		return nil, newError(http.StatusNotFound, codeNotFound, "subscription not found")
+	}
+
+	deliveries, ok := h.dispatcher.Deliveries(sub.ID)
+	if !ok {
+		return nil, newError(http.StatusNotFound, codeNotFound, "subscription not found")
+	}
+
+	return deliveries, nil
+}
+
+func (h *Handler) transactions(r *http.Request, p parser.Parser, address types.Address) ([]types.Transaction, *apiError) {
+	if !h.owns(r, address) {
+		return nil, newError(http.StatusForbidden, codeForbidden, "address is not subscribed by this key")
+	}
+
+	return p.GetTransactions(r.Context(), address), nil
+}
+
+// chain returns parser of chain passed in chain_id query parameter, or parser of the default chain
+func (h *Handler) chain(r *http.Request) (parser.Parser, *apiError) {
+	raw := r.URL.Query().Get("chain_id")
+	if raw == "" {
+		return h.chains.Default(), nil
+	}
+
+	chainID, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, codeInvalidRequest, "invalid chain_id")
+	}
 
-	renderJSON(w, http.StatusOK, transactions)
+	p, ok := h.chains.Get(chainID)
+	if !ok {
+		return nil, newError(http.StatusNotFound, codeUnknownChain, "chain is not parsed")
+	}
+
+	return p, nil
+}
+
+// parseAddress validates address and its EIP-55 checksum if address is mixed-case
+func parseAddress(raw string) (types.Address, *apiError) {
+	address, err := types.ParseAddress(raw)
+	if err != nil {
+		return types.Address{}, newError(http.StatusBadRequest, codeInvalidAddress, err.Error())
+	}
+	return address, nil
+}
+
+func validCallback(callback string) bool {
+	u, err := url.Parse(callback)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 }
 
 func renderJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
@@ -0,0 +1,304 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/api"
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/webhook"
+)
+
+func Test_ErrorEnvelope(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	testCases := []struct {
+		desc     string
+		method   string
+		target   string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{
+			desc:     "Invalid address",
+			method:   http.MethodGet,
+			target:   "/v1/addresses/0x123/transactions",
+			wantCode: http.StatusBadRequest,
+			want:     "invalid_address",
+		},
+		{
+			desc:     "Unknown chain",
+			method:   http.MethodGet,
+			target:   "/v1/block?chain_id=5",
+			wantCode: http.StatusNotFound,
+			want:     "unknown_chain",
+		},
+		{
+			desc:     "Invalid callback",
+			method:   http.MethodPost,
+			target:   "/v1/subscriptions",
+			body:     fmt.Sprintf(`{"address":%q,"callback":"ftp://example.com"}`, alice),
+			wantCode: http.StatusBadRequest,
+			want:     "invalid_callback",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := s.do(tC.method, tC.target, "", tC.body)
+			if rec.Code != tC.wantCode {
+				t.Fatalf("status is not equal, want %d, got %d", tC.wantCode, rec.Code)
+			}
+
+			var resp struct {
+				Error struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode error envelope: %v", err)
+			}
+
+			if resp.Error.Code != tC.want || resp.Error.Message == "" {
+				t.Fatalf("error is not equal, want code %s with message, got %+v", tC.want, resp.Error)
+			}
+		})
+	}
+}
+
+func Test_Deprecated(t *testing.T) {
+	s := newTestServer(t, enableAuth)
+
+	testCases := []struct {
+		desc      string
+		method    string
+		target    string
+		successor string
+	}{
+		{
+			desc:      "Block",
+			method:    http.MethodGet,
+			target:    "/block",
+			successor: "/v1/block",
+		},
+		{
+			desc:      "Status",
+			method:    http.MethodGet,
+			target:    "/status",
+			successor: "/v1/status",
+		},
+		{
+			desc:      "Keys",
+			method:    http.MethodGet,
+			target:    "/admin/keys",
+			successor: "/v1/admin/keys",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := s.do(tC.method, tC.target, adminKey, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status is not equal, want %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+			}
+
+			if got := rec.Header().Get("Deprecation"); got != "true" {
+				t.Fatalf("Deprecation header is not equal, want true, got %q", got)
+			}
+
+			if want, got := "<"+tC.successor+`>; rel="successor-version"`, rec.Header().Get("Link"); got != want {
+				t.Fatalf("Link header is not equal, want %q, got %q", want, got)
+			}
+		})
+	}
+
+	// versioned route is not deprecated
+	if rec := s.do(http.MethodGet, "/v1/admin/keys", adminKey, ""); rec.Header().Get("Deprecation") != "" {
+		t.Fatalf("versioned route must not be deprecated")
+	}
+}
+
+func Test_CreateSubscription(t *testing.T) {
+	s := newTestServer(t, func(c *config.Config) { c.API.MaxBodyBytes = 128 })
+
+	testCases := []struct {
+		desc        string
+		body        string
+		wantCode    int
+		wantCreated bool
+		wantWebhook bool
+	}{
+		{
+			desc:        "New address",
+			body:        fmt.Sprintf(`{"address":%q}`, alice),
+			wantCode:    http.StatusCreated,
+			wantCreated: true,
+		},
+		{
+			desc:     "Already subscribed",
+			body:     fmt.Sprintf(`{"address":%q}`, alice),
+			wantCode: http.StatusOK,
+		},
+		{
+			desc:        "With webhook",
+			body:        fmt.Sprintf(`{"address":%q,"callback":"https://example.com/hook"}`, bob),
+			wantCode:    http.StatusCreated,
+			wantCreated: true,
+			wantWebhook: true,
+		},
+		{
+			desc:     "Invalid body",
+			body:     `{"address":`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			desc:     "Body too large",
+			body:     fmt.Sprintf(`{"address":%q,"callback":"https://example.com/%s"}`, carol, strings.Repeat("a", 128)),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := s.do(http.MethodPost, "/v1/subscriptions", "", tC.body)
+			if rec.Code != tC.wantCode {
+				t.Fatalf("status is not equal, want %d, got %d: %s", tC.wantCode, rec.Code, rec.Body)
+			}
+
+			if rec.Code >= http.StatusBadRequest {
+				return
+			}
+
+			var resp struct {
+				Address string `json:"address"`
+				Created bool   `json:"created"`
+				Webhook *struct {
+					ID  string `json:"id"`
+					URL string `json:"url"`
+				} `json:"webhook"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.Created != tC.wantCreated {
+				t.Fatalf("created is not equal, want %v, got %v", tC.wantCreated, resp.Created)
+			}
+
+			if (resp.Webhook != nil && resp.Webhook.ID != "") != tC.wantWebhook {
+				t.Fatalf("webhook is not equal, want %v, got %+v", tC.wantWebhook, resp.Webhook)
+			}
+		})
+	}
+}
+
+func Test_ChainRouting(t *testing.T) {
+	repo := memory.New()
+	broker := events.NewBroker()
+	client := ethclient.New("http://127.0.0.1:0")
+
+	mainnet := parser.New(config.ChainConfig{ChainID: testChainID}, client, repo, broker)
+	optimism := parser.New(config.ChainConfig{ChainID: 10}, client, repo, broker)
+
+	conf := config.Default()
+
+	dispatcher, err := webhook.New(conf.Webhook, broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.NewStore("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	api.NewHandler(parser.NewChains(mainnet, optimism), broker, dispatcher, keys, repo, conf).Register(mux)
+
+	testCases := []struct {
+		desc        string
+		target      string
+		wantStatus  int
+		wantChainID uint64
+	}{
+		{
+			desc:        "Default chain",
+			target:      "/v1/block",
+			wantStatus:  http.StatusOK,
+			wantChainID: testChainID,
+		},
+		{
+			desc:        "Declared chain",
+			target:      "/v1/block?chain_id=10",
+			wantStatus:  http.StatusOK,
+			wantChainID: 10,
+		},
+		{
+			desc:       "Invalid chain id",
+			target:     "/v1/block?chain_id=op",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			desc:       "Unknown chain",
+			target:     "/v1/status?chain_id=137",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tC.target, nil))
+
+			if rec.Code != tC.wantStatus {
+				t.Fatalf("status is not equal, want %d, got %d: %s", tC.wantStatus, rec.Code, rec.Body)
+			}
+
+			if tC.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got struct {
+				ChainID uint64 `json:"chain_id"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.ChainID != tC.wantChainID {
+				t.Fatalf("chain id is not equal, want %d, got %d", tC.wantChainID, got.ChainID)
+			}
+		})
+	}
+}
+
+func Test_ReadinessBeforeFirstBlock(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	rec := s.do(http.MethodGet, "/readyz", "", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status is not equal, want %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	var resp struct {
+		Checks map[string]struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := "no processed block yet"
+	if got := resp.Checks["lag"].Message; got != want {
+		t.Fatalf("lag message is not equal, want %q, got %q", want, got)
+	}
+}
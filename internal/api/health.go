@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/parser"
+)
+
+const repositoryTimeout = 2 * time.Second
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+type checkResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+func (h *Handler) showHealth(w http.ResponseWriter, r *http.Request) {
+	renderJSON(w, http.StatusOK, checkResult{Status: statusOK})
+}
+
+func (h *Handler) showReadiness(w http.ResponseWriter, r *http.Request) {
+	resp := readinessResponse{
+		Status: statusOK,
+		Checks: map[string]checkResult{
+			"lag":        h.checkLag(),
+			"rpc":        h.checkRPC(),
+			"repository": h.checkRepository(r.Context()),
+		},
+	}
+
+	status := http.StatusOK
+	for _, check := range resp.Checks {
+		if check.Status != statusOK {
+			resp.Status = statusFail
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	renderJSON(w, status, resp)
+}
+
+func (h *Handler) checkLag() checkResult {
+	maxLag := h.conf.Readiness.MaxLagBlocks
+
+	return h.checkChains(func(p parser.Parser) (bool, string) {
+		// lag is unknown until the first block is committed
+		if p.GetCurrentBlock() == 0 {
+			return false, "no processed block yet"
+		}
+
+		lag := p.Lag()
+		if lag > maxLag {
+			return false, fmt.Sprintf("parser is %d blocks behind, max %d", lag, maxLag)
+		}
+
+		return true, fmt.Sprintf("%d blocks behind", lag)
+	})
+}
+
+func (h *Handler) checkRPC() checkResult {
+	maxAge := h.conf.Readiness.MaxRPCAge
+
+	return h.checkChains(func(p parser.Parser) (bool, string) {
+		last := p.LastRPCSuccess()
+		if last.IsZero() {
+			return false, "no successful RPC calls yet"
+		}
+
+		age := time.Since(last).Round(time.Second)
+		if age > maxAge {
+			return false, fmt.Sprintf("last successful RPC call %s ago, max %s", age, maxAge)
+		}
+
+		return true, fmt.Sprintf("last successful RPC call %s ago", age)
+	})
+}
+
+// checkChains runs check for parser of every chain, result fails if any chain fails
+func (h *Handler) checkChains(check func(p parser.Parser) (bool, string)) checkResult {
+	parsers := h.chains.All()
+
+	result := checkResult{Status: statusOK}
+	messages := make([]string, 0, len(parsers))
+
+	for _, p := range parsers {
+		ok, message := check(p)
+		if !ok {
+			result.Status = statusFail
+		}
+
+		if len(parsers) > 1 {
+			message = p.Name() + ": " + message
+		}
+		messages = append(messages, message)
+	}
+
+	result.Message = strings.Join(messages, "; ")
+
+	return result
+}
+
+func (h *Handler) checkRepository(ctx context.Context) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, repositoryTimeout)
+	defer cancel()
+
+	// repository is shared by all chains
+	if err := h.chains.Default().PingRepository(ctx); err != nil {
+		return checkResult{Status: statusFail, Message: err.Error()}
+	}
+
+	return checkResult{Status: statusOK}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// Deprecated routes respond with bare JSON values and errors as strings, use /v1 routes instead
+
+// deprecated marks route as deprecated alias of successor route
+func deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) showCurrentBlock(w http.ResponseWriter, r *http.Request) {
+	p, apiErr := h.chain(r)
+	if apiErr != nil {
+		renderJSON(w, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	renderJSON(w, http.StatusOK, p.GetCurrentBlock())
+}
+
+func (h *Handler) showStatus(w http.ResponseWriter, r *http.Request) {
+	p, apiErr := h.chain(r)
+	if apiErr != nil {
+		renderJSON(w, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	renderJSON(w, http.StatusOK, p.Status())
+}
+
+func (h *Handler) subscribeForTransactions(w http.ResponseWriter, r *http.Request) {
+	address, apiErr := parseAddress(r.URL.Query().Get("address"))
+	if apiErr != nil {
+		renderJSON(w, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	resp, apiErr := h.subscribe(r, address, r.URL.Query().Get("callback"))
+	if apiErr != nil {
+		renderJSON(w, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	if resp.Webhook != nil {
+		renderJSON(w, http.StatusOK, resp.Webhook)
+		return
+	}
+
+	if !resp.Created {
+		renderJSON(w, http.StatusOK, "already subscribed")
+		return
+	}
+
+	renderJSON(w, http.StatusOK, "subscribed")
+}
+
+func (h *Handler) showDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, apiErr := h.deliveries(r, r.PathValue("id"))
+	if apiErr != nil {
+		renderJSON(w, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	renderJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *Handler) showTransactions(w http.ResponseWriter, r *http.Request) {
+	p, apiErr := h.chain(r)
+	if apiErr != nil {
+		renderJSON(w, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	address, apiErr := parseAddress(r.URL.Query().Get("address"))
+	if apiErr != nil {
+		renderJSON(w, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	transactions, apiErr := h.transactions(r, p, address)
+	if apiErr != nil {
+		renderJSON(w, apiErr.Status, apiErr.Message)
+		return
+	}
+
+	renderJSON(w, http.StatusOK, transactions)
+}
+
+func (h *Handler) issueKey(w http.ResponseWriter, r *http.Request) {
+	var req createKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		renderJSON(w, http.StatusBadRequest, "invalid request, name is required")
+		return
+	}
+
+	key, plaintext, err := h.keys.Create(req.Name, req.Admin)
+	if err != nil {
+		slog.Error("failed to create api key", "error", err)
+		renderJSON(w, http.StatusInternalServerError, "failed to create key")
+		return
+	}
+
+	renderJSON(w, http.StatusCreated, createKeyResponse{Key: key, APIKey: plaintext})
+}
+
+func (h *Handler) showKeys(w http.ResponseWriter, r *http.Request) {
+	renderJSON(w, http.StatusOK, h.keys.List())
+}
+
+func (h *Handler) deleteKey(w http.ResponseWriter, r *http.Request) {
+	revoked, err := h.keys.Revoke(r.PathValue("id"))
+	if err != nil {
+		slog.Error("failed to revoke api key", "error", err)
+		renderJSON(w, http.StatusInternalServerError, "failed to revoke key")
+		return
+	}
+
+	if !revoked {
+		renderJSON(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	renderJSON(w, http.StatusOK, "revoked")
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// OpenAPI 3 specification generated from route table and response structs,
+// see https://spec.openapis.org/oas/v3.0.3
+
+const openAPIVersion = "3.0.3"
+
+var pathParamRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// operation documents a route
+type operation struct {
+	summary string
+	// query parameters
+	query []string
+	// request body value, encoded as JSON
+	request any
+	// routes without authentication
+	public     bool
+	deprecated bool
+	responses  []response
+}
+
+type response struct {
+	status      int
+	description string
+	// defaults to application/json
+	contentType string
+	// value of response body, nil if response has no body
+	body any
+}
+
+type openAPI struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       map[string]string               `json:"info"`
+	Paths      map[string]map[string]openAPIOp `json:"paths"`
+	Components map[string]any                  `json:"components"`
+}
+
+type openAPIOp struct {
+	Summary     string                    `json:"summary"`
+	Deprecated  bool                      `json:"deprecated,omitempty"`
+	Parameters  []map[string]any          `json:"parameters,omitempty"`
+	RequestBody map[string]any            `json:"requestBody,omitempty"`
+	Responses   map[string]map[string]any `json:"responses"`
+	Security    []map[string][]string     `json:"security"`
+}
+
+func newOpenAPI(routes []route) openAPI {
+	g := &schemaGenerator{
+		schemas: make(map[string]any),
+		types:   make(map[string]reflect.Type),
+	}
+
+	spec := openAPI{
+		OpenAPI: openAPIVersion,
+		Info: map[string]string{
+			"title":   "Blockchain Parser API",
+			"version": "1.0.0",
+		},
+		Paths: make(map[string]map[string]openAPIOp),
+	}
+
+	routes = append(routes, route{
+		method: http.MethodGet,
+		path:   "/openapi.json",
+		doc: operation{
+			summary:   "OpenAPI specification",
+			public:    true,
+			responses: []response{{status: http.StatusOK, body: map[string]any{}}},
+		},
+	})
+
+	for _, r := range routes {
+		if spec.Paths[r.path] == nil {
+			spec.Paths[r.path] = make(map[string]openAPIOp)
+		}
+		spec.Paths[r.path][strings.ToLower(r.method)] = g.operation(r)
+	}
+
+	spec.Components = map[string]any{
+		"schemas": g.schemas,
+		"securitySchemes": map[string]any{
+			"apiKey": map[string]string{
+				"type": "apiKey",
+				"in":   "header",
+				"name": apiKeyHeader,
+			},
+		},
+	}
+
+	return spec
+}
+
+func (g *schemaGenerator) operation(r route) openAPIOp {
+	op := openAPIOp{
+		Summary:    r.doc.summary,
+		Deprecated: r.doc.deprecated,
+		Responses:  make(map[string]map[string]any),
+		Security:   []map[string][]string{},
+	}
+
+	if !r.doc.public {
+		op.Security = append(op.Security, map[string][]string{"apiKey": {}})
+	}
+
+	for _, match := range pathParamRegex.FindAllStringSubmatch(r.path, -1) {
+		op.Parameters = append(op.Parameters, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]string{"type": "string"},
+		})
+	}
+
+	for _, name := range r.doc.query {
+		op.Parameters = append(op.Parameters, map[string]any{
+			"name":   name,
+			"in":     "query",
+			"schema": map[string]string{"type": "string"},
+		})
+	}
+
+	if r.doc.request != nil {
+		op.RequestBody = map[string]any{
+			"required": true,
+			"content":  g.content("application/json", r.doc.request),
+		}
+	}
+
+	for _, resp := range r.doc.responses {
+		description := resp.description
+		if description == "" {
+			description = http.StatusText(resp.status)
+		}
+
+		doc := map[string]any{"description": description}
+		if resp.body != nil {
+			contentType := resp.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			doc["content"] = g.content(contentType, resp.body)
+		}
+
+		op.Responses[strconv.Itoa(resp.status)] = doc
+	}
+
+	// errors of versioned routes use envelope
+	if strings.HasPrefix(r.path, "/v1/") {
+		op.Responses["default"] = map[string]any{
+			"description": "Error",
+			"content":     g.content("application/json", errorResponse{}),
+		}
+	}
+
+	return op
+}
+
+func (g *schemaGenerator) content(contentType string, body any) map[string]any {
+	return map[string]any{
+		contentType: map[string]any{
+			"schema": g.schema(reflect.TypeOf(body)),
+		},
+	}
+}
+
+// schemaGenerator builds JSON schemas of Go types, named structs are placed to components
+type schemaGenerator struct {
+	schemas map[string]any
+	types   map[string]reflect.Type
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.ref(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// ref places named struct schema to components and returns reference to it
+func (g *schemaGenerator) ref(t reflect.Type) map[string]any {
+	name := schemaName(t.Name())
+	if existing, ok := g.types[name]; ok && existing != t {
+		// same type name in different packages
+		name = schemaName(t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]) + name
+	}
+
+	if _, ok := g.types[name]; !ok {
+		g.types[name] = t
+		// placeholder prevents infinite recursion on self referencing types
+		g.schemas[name] = nil
+		g.schemas[name] = g.structSchema(t)
+	}
+
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+
+	g.collectFields(t, properties, &required)
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func (g *schemaGenerator) collectFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// embedded structs without name are flattened
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.collectFields(field.Type, properties, required)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = g.schema(field.Type)
+
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func schemaName(name string) string {
+	if name == "" {
+		return name
+	}
+
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+
+	return string(runes)
+}
@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/api"
+)
+
+// recordingRouter remembers every registered pattern
+type recordingRouter struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (r *recordingRouter) Handle(pattern string, handler http.Handler) {
+	r.patterns = append(r.patterns, pattern)
+	r.ServeMux.Handle(pattern, handler)
+}
+
+type spec struct {
+	OpenAPI string `json:"openapi"`
+	Paths   map[string]map[string]struct {
+		Summary   string         `json:"summary"`
+		Responses map[string]any `json:"responses"`
+	} `json:"paths"`
+}
+
+func Test_OpenAPIDocumentsAllRoutes(t *testing.T) {
+	testCases := []struct {
+		desc string
+		conf config.Config
+	}{
+		{
+			desc: "Auth Disabled",
+			conf: config.Config{},
+		},
+		{
+			desc: "Auth Enabled",
+			conf: config.Config{Auth: config.AuthConfig{Enabled: true}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			router := &recordingRouter{ServeMux: http.NewServeMux()}
+			api.NewHandler(nil, nil, nil, nil, nil, tC.conf).Register(router)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("failed to get openapi.json, status %d", rec.Code)
+			}
+
+			var s spec
+			if err := json.NewDecoder(rec.Body).Decode(&s); err != nil {
+				t.Fatalf("failed to decode openapi.json: %v", err)
+			}
+
+			if !strings.HasPrefix(s.OpenAPI, "3.") {
+				t.Fatalf("openapi version is not 3.x, got %s", s.OpenAPI)
+			}
+
+			documented := 0
+			for _, ops := range s.Paths {
+				documented += len(ops)
+			}
+
+			if documented != len(router.patterns) {
+				t.Fatalf("documented operations count is not equal to registered routes, want %d, got %d", len(router.patterns), documented)
+			}
+
+			for _, pattern := range router.patterns {
+				method, path, _ := strings.Cut(pattern, " ")
+
+				op, ok := s.Paths[path][strings.ToLower(method)]
+				if !ok {
+					t.Fatalf("route %s is not documented", pattern)
+				}
+
+				if op.Summary == "" {
+					t.Fatalf("route %s has no summary", pattern)
+				}
+
+				if len(op.Responses) == 0 {
+					t.Fatalf("route %s has no responses", pattern)
+				}
+			}
+		})
+	}
+}
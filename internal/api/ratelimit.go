@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/ratelimit"
+)
+
+// limitByIP rejects requests of clients exceeded per IP rate limit
+func (h *Handler) limitByIP(next http.Handler) http.Handler {
+	return limit(h.ipLimiter, clientIP, next)
+}
+
+// limitByKey rejects requests of authenticated keys exceeded per key rate limit
+func (h *Handler) limitByKey(next http.Handler) http.Handler {
+	return limit(h.keyLimiter, func(r *http.Request) string {
+		key, _ := auth.FromContext(r.Context())
+		return key.ID
+	}, next)
+}
+
+func limit(limiter *ratelimit.Limiter, keyFunc func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := limiter.Allow(keyFunc(r)); !ok {
+			renderError(w, tooManyRequests("rate limit exceeded", wait))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
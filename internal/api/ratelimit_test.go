@@ -0,0 +1,131 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/config"
+)
+
+const carol = "0x22a7a914cf352f7361c199188a23da94fe71b277"
+
+func Test_RateLimit(t *testing.T) {
+	s := newTestServer(t, func(c *config.Config) {
+		c.RateLimit.PerIP = config.LimitConfig{Rate: 1, Burst: 2}
+	})
+
+	for i := 0; i < 2; i++ {
+		if rec := s.do(http.MethodGet, "/v1/block", "", ""); rec.Code != http.StatusOK {
+			t.Fatalf("status of request %d is not equal, want %d, got %d", i, http.StatusOK, rec.Code)
+		}
+	}
+
+	rec := s.do(http.MethodGet, "/v1/block", "", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status is not equal, want %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("Retry-After is not equal, want 1, got %q", got)
+	}
+}
+
+func Test_SubscriptionLimit(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		configure func(c *config.Config)
+		// requests are sent with admin key instead of tenant key, if auth is enabled
+		admin bool
+		// statuses of subscriptions to alice, bob, alice again and carol
+		want []int
+	}{
+		{
+			desc:      "Global limit without auth",
+			configure: func(c *config.Config) { c.RateLimit.MaxSubscriptions = 2 },
+			want:      []int{http.StatusCreated, http.StatusCreated, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			desc:      "Unlimited without auth",
+			configure: func(c *config.Config) { c.RateLimit.MaxSubscriptionsPerKey = 2 },
+			want:      []int{http.StatusCreated, http.StatusCreated, http.StatusOK, http.StatusCreated},
+		},
+		{
+			desc: "Per key limit",
+			configure: func(c *config.Config) {
+				c.Auth.Enabled = true
+				c.RateLimit.MaxSubscriptionsPerKey = 2
+			},
+			want: []int{http.StatusCreated, http.StatusCreated, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			desc: "Admin key is unlimited",
+			configure: func(c *config.Config) {
+				c.Auth.Enabled = true
+				c.RateLimit.MaxSubscriptionsPerKey = 2
+			},
+			admin: true,
+			want:  []int{http.StatusCreated, http.StatusCreated, http.StatusOK, http.StatusCreated},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := newTestServer(t, tC.configure)
+
+			key := ""
+			if s.conf.Auth.Enabled {
+				key = adminKey
+				if !tC.admin {
+					_, plaintext, err := s.keys.Create("tenant", false)
+					if err != nil {
+						t.Fatal(err)
+					}
+					key = plaintext
+				}
+			}
+
+			for i, address := range []string{alice.Hex(), bob.Hex(), alice.Hex(), carol} {
+				rec := s.do(http.MethodPost, "/v1/subscriptions", key, fmt.Sprintf(`{"address":%q}`, address))
+				if rec.Code != tC.want[i] {
+					t.Fatalf("status of subscription %d is not equal, want %d, got %d: %s", i, tC.want[i], rec.Code, rec.Body)
+				}
+
+				if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+					t.Fatalf("Retry-After is missing")
+				}
+			}
+		})
+	}
+}
+
+func Test_BulkSubscriptionLimit(t *testing.T) {
+	s := newTestServer(t, func(c *config.Config) { c.RateLimit.MaxSubscriptions = 2 })
+
+	rec := s.do(http.MethodPost, "/v1/subscriptions/bulk", "", fmt.Sprintf(`[%q,%q,%q]`, alice, bob, carol))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status is not equal, want %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Subscribed int `json:"subscribed"`
+		Failed     int `json:"failed"`
+		Results    []struct {
+			Error *struct {
+				Code       string `json:"code"`
+				RetryAfter int    `json:"retry_after"`
+			} `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Subscribed != 2 || resp.Failed != 1 {
+		t.Fatalf("subscribed and failed are not equal, want 2 and 1, got %d and %d", resp.Subscribed, resp.Failed)
+	}
+
+	if e := resp.Results[2].Error; e == nil || e.Code != "subscription_limit_exceeded" || e.RetryAfter <= 0 {
+		t.Fatalf("error of address over limit is not equal, got %+v", e)
+	}
+}
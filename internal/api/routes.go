@@ -0,0 +1,344 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/metrics"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/snapshot"
+	"github.com/avelex/blockchain-parser/internal/types"
+	"github.com/avelex/blockchain-parser/internal/webhook"
+)
+
+// Router is implemented by *http.ServeMux
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+type route struct {
+	method  string
+	path    string
+	handler http.Handler
+	doc     operation
+}
+
+func (r route) pattern() string {
+	return r.method + " " + r.path
+}
+
+func (h *Handler) Register(m Router) {
+	routes := h.routes()
+
+	for _, r := range routes {
+		m.Handle(r.pattern(), r.handler)
+	}
+
+	spec := newOpenAPI(routes)
+	m.Handle("GET /openapi.json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renderJSON(w, http.StatusOK, spec)
+	}))
+}
+
+// routes returns all routes with documentation used to generate OpenAPI specification
+func (h *Handler) routes() []route {
+	routes := []route{
+		{
+			method:  http.MethodGet,
+			path:    "/v1/block",
+			handler: h.authenticated(h.getBlock),
+			doc: operation{
+				summary:   "Last parsed block of chain, the default chain if chain_id is not set",
+				query:     []string{"chain_id"},
+				responses: []response{{status: http.StatusOK, body: blockResponse{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/status",
+			handler: h.authenticated(h.getStatus),
+			doc: operation{
+				summary:   "Sync status of chain with lag and ETA",
+				query:     []string{"chain_id"},
+				responses: []response{{status: http.StatusOK, body: parser.Status{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/chains",
+			handler: h.authenticated(h.listChains),
+			doc: operation{
+				summary:   "Sync status of all parsed chains, the first one is the default chain",
+				responses: []response{{status: http.StatusOK, body: chainsResponse{}}},
+			},
+		},
+		{
+			method:  http.MethodPost,
+			path:    "/v1/subscriptions",
+			handler: h.authenticated(h.createSubscription),
+			doc: operation{
+				summary: "Subscribe for transactions of address on all chains, optionally with webhook delivery",
+				request: subscriptionRequest{},
+				responses: []response{
+					{status: http.StatusCreated, description: "Subscribed", body: subscriptionResponse{}},
+					{status: http.StatusOK, description: "Already subscribed", body: subscriptionResponse{}},
+				},
+			},
+		},
+		{
+			method:  http.MethodPost,
+			path:    "/v1/subscriptions/bulk",
+			handler: h.authenticated(h.createSubscriptions),
+			doc: operation{
+				summary:   "Subscribe for transactions of many addresses passed as JSON array or newline-delimited text",
+				request:   []string{},
+				responses: []response{{status: http.StatusOK, body: bulkSubscriptionResponse{}}},
+			},
+		},
+		{
+			method:  http.MethodPost,
+			path:    "/v1/transactions/query",
+			handler: h.authenticated(h.queryTransactions),
+			doc: operation{
+				summary:   "Transactions of many subscribed addresses",
+				query:     []string{"chain_id"},
+				request:   transactionsQueryRequest{},
+				responses: []response{{status: http.StatusOK, body: transactionsQueryResponse{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/subscriptions/{id}/deliveries",
+			handler: h.authenticated(h.listDeliveries),
+			doc: operation{
+				summary:   "Webhook delivery attempts of subscription",
+				responses: []response{{status: http.StatusOK, body: deliveriesResponse{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/addresses/{address}/transactions",
+			handler: h.authenticated(h.listTransactions),
+			doc: operation{
+				summary:   "Inbound and outbound transactions of subscribed address",
+				query:     []string{"chain_id"},
+				responses: []response{{status: http.StatusOK, body: transactionsResponse{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/export",
+			handler: h.authenticated(h.exportTransactions),
+			doc: operation{
+				summary: "Download transactions of subscribed address as csv, jsonl (default) or parquet file",
+				query:   []string{"address", "format", "chain_id"},
+				responses: []response{
+					{status: http.StatusOK, contentType: "application/octet-stream", body: ""},
+				},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/v1/ws",
+			handler: h.authenticated(h.streamEvents),
+			doc: operation{
+				summary:   "WebSocket stream of transaction, confirmation and reorg events",
+				responses: []response{{status: http.StatusSwitchingProtocols, description: "Switching to WebSocket protocol"}},
+			},
+		},
+
+		// deprecated routes, kept for backward compatibility
+		{
+			method:  http.MethodGet,
+			path:    "/block",
+			handler: deprecated("/v1/block", h.authenticated(h.showCurrentBlock)),
+			doc: operation{
+				summary:    "Last parsed block",
+				deprecated: true,
+				query:      []string{"chain_id"},
+				responses:  []response{{status: http.StatusOK, body: 0}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/status",
+			handler: deprecated("/v1/status", h.authenticated(h.showStatus)),
+			doc: operation{
+				summary:    "Sync status with lag and ETA",
+				deprecated: true,
+				query:      []string{"chain_id"},
+				responses:  []response{{status: http.StatusOK, body: parser.Status{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/subscribe",
+			handler: deprecated("/v1/subscriptions", h.authenticated(h.subscribeForTransactions)),
+			doc: operation{
+				summary:    "Subscribe for transactions of address",
+				deprecated: true,
+				query:      []string{"address", "callback"},
+				responses:  []response{{status: http.StatusOK, body: webhook.Subscription{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/transactions",
+			handler: deprecated("/v1/addresses/{address}/transactions", h.authenticated(h.showTransactions)),
+			doc: operation{
+				summary:    "Transactions of subscribed address",
+				deprecated: true,
+				query:      []string{"address", "chain_id"},
+				responses:  []response{{status: http.StatusOK, body: []types.Transaction{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/subscriptions/{id}/deliveries",
+			handler: deprecated("/v1/subscriptions/{id}/deliveries", h.authenticated(h.showDeliveries)),
+			doc: operation{
+				summary:    "Webhook delivery attempts of subscription",
+				deprecated: true,
+				responses:  []response{{status: http.StatusOK, body: []webhook.Delivery{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/ws",
+			handler: deprecated("/v1/ws", h.authenticated(h.streamEvents)),
+			doc: operation{
+				summary:    "WebSocket stream of events",
+				deprecated: true,
+				responses:  []response{{status: http.StatusSwitchingProtocols, description: "Switching to WebSocket protocol"}},
+			},
+		},
+
+		// probes and scrapers are not authenticated
+		{
+			method:  http.MethodGet,
+			path:    "/metrics",
+			handler: metrics.Default.Handler(),
+			doc: operation{
+				summary:   "Prometheus metrics",
+				public:    true,
+				responses: []response{{status: http.StatusOK, contentType: "text/plain", body: ""}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/healthz",
+			handler: http.HandlerFunc(h.showHealth),
+			doc: operation{
+				summary:   "Liveness probe",
+				public:    true,
+				responses: []response{{status: http.StatusOK, body: checkResult{}}},
+			},
+		},
+		{
+			method:  http.MethodGet,
+			path:    "/readyz",
+			handler: http.HandlerFunc(h.showReadiness),
+			doc: operation{
+				summary: "Readiness probe",
+				public:  true,
+				responses: []response{
+					{status: http.StatusOK, description: "Ready", body: readinessResponse{}},
+					{status: http.StatusServiceUnavailable, description: "Not ready", body: readinessResponse{}},
+				},
+			},
+		},
+	}
+
+	if h.conf.Auth.Enabled {
+		routes = append(routes,
+			route{
+				method:  http.MethodPost,
+				path:    "/v1/admin/keys",
+				handler: h.admin(h.createKey),
+				doc: operation{
+					summary:   "Create API key",
+					request:   createKeyRequest{},
+					responses: []response{{status: http.StatusCreated, body: createKeyResponse{}}},
+				},
+			},
+			route{
+				method:  http.MethodGet,
+				path:    "/v1/admin/keys",
+				handler: h.admin(h.listKeys),
+				doc: operation{
+					summary:   "List API keys",
+					responses: []response{{status: http.StatusOK, body: keysResponse{}}},
+				},
+			},
+			route{
+				method:  http.MethodDelete,
+				path:    "/v1/admin/keys/{id}",
+				handler: h.admin(h.revokeKey),
+				doc: operation{
+					summary:   "Revoke API key",
+					responses: []response{{status: http.StatusNoContent, description: "Revoked"}},
+				},
+			},
+
+			// snapshot exposes data of all keys, so it's not served without admin key
+			route{
+				method:  http.MethodGet,
+				path:    "/v1/snapshot",
+				handler: h.admin(h.getSnapshot),
+				doc: operation{
+					summary:   "Snapshot of subscriptions, transactions and checkpoint of all chains",
+					responses: []response{{status: http.StatusOK, body: snapshot.Snapshot{}}},
+				},
+			},
+			route{
+				method:  http.MethodPost,
+				path:    "/v1/snapshot/restore",
+				handler: h.admin(h.restoreSnapshot),
+				doc: operation{
+					summary: "Restore snapshot after validating chain IDs and block continuity",
+					request: snapshot.Snapshot{},
+					responses: []response{
+						{status: http.StatusOK, body: restoreResponse{}},
+						{status: http.StatusConflict, description: "Snapshot is not continuous with processed blocks", body: errorResponse{}},
+						{status: http.StatusRequestEntityTooLarge, description: "Snapshot exceeds api.max_snapshot_bytes", body: errorResponse{}},
+					},
+				},
+			},
+
+			// deprecated routes, kept for backward compatibility
+			route{
+				method:  http.MethodPost,
+				path:    "/admin/keys",
+				handler: deprecated("/v1/admin/keys", h.admin(h.issueKey)),
+				doc: operation{
+					summary:    "Create API key",
+					deprecated: true,
+					request:    createKeyRequest{},
+					responses:  []response{{status: http.StatusCreated, body: createKeyResponse{}}},
+				},
+			},
+			route{
+				method:  http.MethodGet,
+				path:    "/admin/keys",
+				handler: deprecated("/v1/admin/keys", h.admin(h.showKeys)),
+				doc: operation{
+					summary:    "List API keys",
+					deprecated: true,
+					responses:  []response{{status: http.StatusOK, body: []auth.Key{}}},
+				},
+			},
+			route{
+				method:  http.MethodDelete,
+				path:    "/admin/keys/{id}",
+				handler: deprecated("/v1/admin/keys/{id}", h.admin(h.deleteKey)),
+				doc: operation{
+					summary:    "Revoke API key",
+					deprecated: true,
+					responses:  []response{{status: http.StatusOK, body: "revoked"}},
+				},
+			},
+		)
+	}
+
+	return routes
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/snapshot"
+)
+
+type restoredChain struct {
+	ChainID       uint64 `json:"chain_id"`
+	Checkpoint    int    `json:"checkpoint"`
+	Subscriptions int    `json:"subscriptions"`
+	Transactions  int    `json:"transactions"`
+	// blocks missing in snapshot, to be filled by backfill
+	Gaps []repository.BlockRange `json:"gaps,omitempty"`
+}
+
+type restoreResponse struct {
+	Chains []restoredChain `json:"chains"`
+}
+
+// getSnapshot returns repository state of all parsed chains
+func (h *Handler) getSnapshot(w http.ResponseWriter, r *http.Request) {
+	s, err := snapshot.Take(r.Context(), h.repo, h.chainIDs())
+	if err != nil {
+		slog.Error("failed to take snapshot", "error", err)
+		renderError(w, newError(http.StatusInternalServerError, codeInternal, "failed to take snapshot"))
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="snapshot.json"`)
+	renderJSON(w, http.StatusOK, s)
+}
+
+// restoreSnapshot loads snapshot into repository and subscribes its addresses
+func (h *Handler) restoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.conf.API.MaxSnapshotBytes)
+
+	var s snapshot.Snapshot
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		renderError(w, bodyError(err, "invalid json body"))
+		return
+	}
+
+	if err := s.Validate(h.chainIDs()); err != nil {
+		renderError(w, newError(http.StatusBadRequest, codeInvalidRequest, err.Error()))
+		return
+	}
+
+	resp := restoreResponse{Chains: make([]restoredChain, 0, len(s.Chains))}
+
+	for _, c := range s.Chains {
+		p, _ := h.chains.Get(c.ChainID)
+
+		// parser without processed blocks has already chosen start block, so blocks between
+		// checkpoint and start block would be left as a gap
+		if p.GetCurrentBlock() == 0 {
+			renderError(w, newError(http.StatusConflict, codeConflict,
+				"parser has not processed blocks yet, restore snapshot with restore command before start"))
+			return
+		}
+
+		// running parser would process blocks of snapshot again
+		if c.Checkpoint >= p.GetCurrentBlock() {
+			renderError(w, newError(http.StatusConflict, codeConflict,
+				"snapshot is ahead of running parser, restore it with restore command before start"))
+			return
+		}
+
+		restored := restoredChain{
+			ChainID:       c.ChainID,
+			Checkpoint:    c.Checkpoint,
+			Subscriptions: len(c.Subscriptions),
+			Gaps:          c.Gaps(),
+		}
+		for _, txs := range c.Transactions {
+			restored.Transactions += len(txs)
+		}
+
+		resp.Chains = append(resp.Chains, restored)
+	}
+
+	err := snapshot.Restore(r.Context(), h.repo, s)
+	if errors.Is(err, snapshot.ErrNotContinuous) {
+		renderError(w, newError(http.StatusConflict, codeConflict, err.Error()))
+		return
+	}
+	if err != nil {
+		slog.Error("failed to restore snapshot", "error", err)
+		renderError(w, newError(http.StatusInternalServerError, codeInternal, "failed to restore snapshot"))
+		return
+	}
+
+	for _, c := range s.Chains {
+		p, _ := h.chains.Get(c.ChainID)
+		for _, address := range c.Subscriptions {
+			p.Subscribe(address)
+		}
+	}
+
+	slog.Info("Restored snapshot", "chains", resp.Chains)
+
+	renderJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) chainIDs() []uint64 {
+	ids := make([]uint64, 0, len(h.chains.All()))
+	for _, p := range h.chains.All() {
+		ids = append(ids, p.ChainID())
+	}
+	return ids
+}
@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/config"
+)
+
+func Test_Snapshot(t *testing.T) {
+	restoreBody := `{"version":1,"chains":[{"chain_id":1,"checkpoint":10,"processed":[{"from":1,"to":10}]}]}`
+
+	testCases := []struct {
+		desc   string
+		auth   bool
+		method string
+		target string
+		key    string
+		body   string
+		want   int
+	}{
+		{
+			desc:   "Snapshot without auth",
+			method: http.MethodGet,
+			target: "/v1/snapshot",
+			want:   http.StatusNotFound,
+		},
+		{
+			desc:   "Restore without auth",
+			method: http.MethodPost,
+			target: "/v1/snapshot/restore",
+			body:   restoreBody,
+			want:   http.StatusNotFound,
+		},
+		{
+			desc:   "Snapshot with non admin key",
+			auth:   true,
+			method: http.MethodGet,
+			target: "/v1/snapshot",
+			key:    "tenant",
+			want:   http.StatusForbidden,
+		},
+		{
+			desc:   "Snapshot with admin key",
+			auth:   true,
+			method: http.MethodGet,
+			target: "/v1/snapshot",
+			key:    adminKey,
+			want:   http.StatusOK,
+		},
+		{
+			desc:   "Restore exceeding body limit",
+			auth:   true,
+			method: http.MethodPost,
+			target: "/v1/snapshot/restore",
+			key:    adminKey,
+			body:   `{"chains":[` + strings.Repeat(" ", 1024) + `]}`,
+			want:   http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:   "Restore before the first processed block",
+			auth:   true,
+			method: http.MethodPost,
+			target: "/v1/snapshot/restore",
+			key:    adminKey,
+			body:   restoreBody,
+			want:   http.StatusConflict,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := newTestServer(t, func(c *config.Config) {
+				c.Auth.Enabled = tC.auth
+				c.API.MaxSnapshotBytes = 512
+			})
+
+			// keys are not shared between servers, so tenant key is created for the test case
+			key := tC.key
+			if key == "tenant" {
+				_, plaintext, err := s.keys.Create("tenant", false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				key = plaintext
+			}
+
+			rec := s.do(tC.method, tC.target, key, tC.body)
+			if rec.Code != tC.want {
+				t.Fatalf("status is not equal, want %d, got %d: %s", tC.want, rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/types"
+	"github.com/avelex/blockchain-parser/internal/websocket"
+)
+
+const (
+	actionSubscribe   = "subscribe"
+	actionUnsubscribe = "unsubscribe"
+)
+
+const (
+	messageSubscribed   = "subscribed"
+	messageUnsubscribed = "unsubscribed"
+	messageError        = "error"
+)
+
+// clientMessage is a message sent by websocket client
+type clientMessage struct {
+	Action    string   `json:"action"`
+	Addresses []string `json:"addresses"`
+}
+
+// serverMessage is a reply to client message, events are sent as is
+type serverMessage struct {
+	Type      string          `json:"type"`
+	Addresses []types.Address `json:"addresses,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type wsSession struct {
+	key  auth.Key
+	conn *websocket.Conn
+	send chan []byte
+
+	mu        *sync.RWMutex
+	addresses map[types.Address]struct{}
+}
+
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		slog.Warn("failed to upgrade websocket connection", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	key, _ := auth.FromContext(r.Context())
+
+	s := &wsSession{
+		key:       key,
+		conn:      conn,
+		send:      make(chan []byte, h.conf.WebSocket.SendBuffer),
+		mu:        &sync.RWMutex{},
+		addresses: make(map[types.Address]struct{}),
+	}
+
+	eventsChan, cancel := h.broker.Subscribe(h.conf.WebSocket.SendBuffer)
+	defer cancel()
+
+	done := make(chan struct{})
+	writerDone := make(chan struct{})
+
+	go func() {
+		defer close(writerDone)
+		h.writeMessages(s, done)
+	}()
+
+	go func() {
+		h.forwardEvents(s, eventsChan, done)
+	}()
+
+	h.readMessages(s)
+
+	close(done)
+	<-writerDone
+}
+
+// readMessages handles client messages until connection closed
+func (h *Handler) readMessages(s *wsSession) {
+	for {
+		_, payload, err := s.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) && !errors.Is(err, websocket.ErrClosed) {
+				slog.Debug("websocket read failed", "error", err)
+			}
+			return
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			s.reply(serverMessage{Type: messageError, Error: "invalid message"})
+			continue
+		}
+
+		if !s.reply(h.handleClientMessage(s, msg)) {
+			return
+		}
+	}
+}
+
+func (h *Handler) handleClientMessage(s *wsSession, msg clientMessage) serverMessage {
+	addresses := make([]types.Address, 0, len(msg.Addresses))
+	for _, raw := range msg.Addresses {
+		address, err := types.ParseAddress(raw)
+		if err != nil {
+			return serverMessage{Type: messageError, Error: err.Error() + " " + raw}
+		}
+		addresses = append(addresses, address)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg.Action {
+	case actionSubscribe:
+		limit, tracked := h.subscriptionLimit(s.key)
+
+		for _, address := range addresses {
+			if tracked {
+				_, err := h.keys.Grant(s.key.ID, address, limit)
+				if errors.Is(err, auth.ErrQuotaExceeded) {
+					return serverMessage{Type: messageError, Error: "subscriptions limit exceeded"}
+				}
+				if err != nil {
+					slog.Error("failed to grant address", "key", s.key.ID, "address", address, "error", err)
+					return serverMessage{Type: messageError, Error: "failed to subscribe " + address.String()}
+				}
+			}
+
+			h.chains.Subscribe(address)
+
+			s.addresses[address] = struct{}{}
+		}
+		return serverMessage{Type: messageSubscribed, Addresses: addresses}
+	case actionUnsubscribe:
+		for _, address := range addresses {
+			delete(s.addresses, address)
+		}
+		return serverMessage{Type: messageUnsubscribed, Addresses: addresses}
+	default:
+		return serverMessage{Type: messageError, Error: "unknown action " + msg.Action}
+	}
+}
+
+// forwardEvents passes parser events of subscribed addresses to the connection
+func (h *Handler) forwardEvents(s *wsSession, eventsChan <-chan events.Event, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-eventsChan:
+			if !ok {
+				s.disconnect("slow consumer")
+				return
+			}
+
+			if e.Type != events.TypeReorg && !s.subscribed(e.Address) {
+				continue
+			}
+
+			payload, err := json.Marshal(e)
+			if err != nil {
+				slog.Error("failed to marshal event", "error", err)
+				continue
+			}
+
+			if !s.enqueue(payload) {
+				return
+			}
+		}
+	}
+}
+
+func (h *Handler) writeMessages(s *wsSession, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+			s.conn.WriteClose(websocket.CloseGoingAway, "")
+			return
+		case payload := <-s.send:
+			s.conn.SetWriteDeadline(time.Now().Add(h.conf.WebSocket.WriteTimeout))
+			if err := s.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				// unblock reader
+				s.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *wsSession) reply(msg serverMessage) bool {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return false
+	}
+	return s.enqueue(payload)
+}
+
+// enqueue puts message to the send buffer, disconnects client if buffer is full
+func (s *wsSession) enqueue(payload []byte) bool {
+	select {
+	case s.send <- payload:
+		return true
+	default:
+		s.disconnect("slow consumer")
+		return false
+	}
+}
+
+func (s *wsSession) disconnect(reason string) {
+	slog.Warn("Disconnecting websocket client", "reason", reason)
+	s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	s.conn.WriteClose(websocket.ClosePolicyViolation, reason)
+	s.conn.Close()
+}
+
+func (s *wsSession) subscribed(address types.Address) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.addresses[address]
+	return ok
+}
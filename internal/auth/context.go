@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"context"
+)
+
+type contextKey struct{}
+
+// NewContext returns context carrying authenticated key
+func NewContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext returns key of authenticated request
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/fsutil"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+const keyPrefix = "bp_"
+
+// bootstrapKeyID identifies admin key from config, it's never persisted
+const bootstrapKeyID = "config"
+
+type Key struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Admin     bool      `json:"admin"`
+	CreatedAt time.Time `json:"created_at"`
+	// hex encoded SHA-256 of the key, plaintext is shown only once on creation
+	Hash string `json:"-"`
+}
+
+// storedKey is a Key with hash, Key itself hides hash from API responses
+type storedKey struct {
+	Key
+	Hash string `json:"hash"`
+}
+
+type state struct {
+	Keys []storedKey `json:"keys"`
+	// addresses by key id
+	Addresses map[string][]types.Address `json:"addresses"`
+}
+
+// Store keeps hashed API keys and addresses subscribed by each key
+type Store struct {
+	mu        *sync.RWMutex
+	keys      map[string]*Key // by hash
+	addresses map[string]map[types.Address]struct{}
+
+	path string
+}
+
+// NewStore loads keys from path, adminKey from config is accepted as admin key if not empty
+func NewStore(path, adminKey string) (*Store, error) {
+	s := &Store{
+		mu:        &sync.RWMutex{},
+		keys:      make(map[string]*Key),
+		addresses: make(map[string]map[types.Address]struct{}),
+		path:      path,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("failed to load keys: %w", err)
+	}
+
+	if adminKey != "" {
+		hash := hashKey(adminKey)
+		s.keys[hash] = &Key{
+			ID:    bootstrapKeyID,
+			Name:  "config admin key",
+			Admin: true,
+			Hash:  hash,
+		}
+	}
+
+	return s, nil
+}
+
+// Create generates new key, returned plaintext can't be recovered later
+func (s *Store) Create(name string, admin bool) (Key, string, error) {
+	plaintext := keyPrefix + randomHex(24)
+
+	key := &Key{
+		ID:        randomHex(8),
+		Name:      name,
+		Admin:     admin,
+		CreatedAt: time.Now().UTC(),
+		Hash:      hashKey(plaintext),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.Hash] = key
+
+	if err := s.persist(); err != nil {
+		delete(s.keys, key.Hash)
+		return Key{}, "", err
+	}
+
+	return *key, plaintext, nil
+}
+
+func (s *Store) Authenticate(plaintext string) (Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[hashKey(plaintext)]
+	if !ok {
+		return Key{}, false
+	}
+
+	return *key, true
+}
+
+func (s *Store) List() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		if key.ID != bootstrapKeyID {
+			keys = append(keys, *key)
+		}
+	}
+
+	return keys
+}
+
+// Revoke deletes key and its addresses, returns false if key not found
+func (s *Store) Revoke(id string) (bool, error) {
+	if id == bootstrapKeyID {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, key := range s.keys {
+		if key.ID != id {
+			continue
+		}
+
+		owned := s.addresses[id]
+
+		delete(s.keys, hash)
+		delete(s.addresses, id)
+
+		if err := s.persist(); err != nil {
+			s.keys[hash] = key
+			if owned != nil {
+				s.addresses[id] = owned
+			}
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// ErrQuotaExceeded is returned when key already owns as many addresses as allowed
+var ErrQuotaExceeded = errors.New("subscriptions quota exceeded")
+
+// GrantStatus is result of granting address to key
+type GrantStatus int
+
+const (
+	// address was already owned by key
+	Owned GrantStatus = iota
+	Granted
+	// address wasn't granted as key owns limit addresses
+	QuotaExceeded
+)
+
+// Grant records address as subscribed by key, returns false if key already owns address.
+// New address is refused with ErrQuotaExceeded if key owns limit addresses, zero limit is unlimited.
+func (s *Store) Grant(keyID string, address types.Address, limit int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.addresses[keyID][address]; ok {
+		return false, nil
+	}
+
+	if limit > 0 && len(s.addresses[keyID]) >= limit {
+		return false, ErrQuotaExceeded
+	}
+
+	if s.addresses[keyID] == nil {
+		s.addresses[keyID] = make(map[types.Address]struct{})
+	}
+
+	s.addresses[keyID][address] = struct{}{}
+
+	if err := s.persist(); err != nil {
+		s.revoke(keyID, []types.Address{address})
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GrantAll records addresses as subscribed by key with single write, returns status of each address.
+// New addresses are granted in order until key owns limit addresses, zero limit is unlimited.
+func (s *Store) GrantAll(keyID string, addresses []types.Address, limit int) ([]GrantStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var added []types.Address
+
+	statuses := make([]GrantStatus, len(addresses))
+	for i, address := range addresses {
+		if _, ok := s.addresses[keyID][address]; ok {
+			statuses[i] = Owned
+			continue
+		}
+
+		if limit > 0 && len(s.addresses[keyID]) >= limit {
+			statuses[i] = QuotaExceeded
+			continue
+		}
+
+		if s.addresses[keyID] == nil {
+			s.addresses[keyID] = make(map[types.Address]struct{})
+		}
+
+		s.addresses[keyID][address] = struct{}{}
+		statuses[i] = Granted
+		added = append(added, address)
+	}
+
+	if len(added) == 0 {
+		return statuses, nil
+	}
+
+	if err := s.persist(); err != nil {
+		s.revoke(keyID, added)
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// revoke forgets addresses granted to key, must be called with mutex held
+func (s *Store) revoke(keyID string, addresses []types.Address) {
+	for _, address := range addresses {
+		delete(s.addresses[keyID], address)
+	}
+
+	if len(s.addresses[keyID]) == 0 {
+		delete(s.addresses, keyID)
+	}
+}
+
+// HasAdmin reports whether any admin key is accepted, either from config or persisted
+func (s *Store) HasAdmin() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Admin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) Owns(keyID string, address types.Address) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.addresses[keyID][address]
+	return ok
+}
+
+// Count returns number of addresses subscribed by key
+func (s *Store) Count(keyID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.addresses[keyID])
+}
+
+// AllAddresses returns addresses subscribed by any key
+func (s *Store) AllAddresses() []types.Address {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var addresses []types.Address
+	for _, owned := range s.addresses {
+		for address := range owned {
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
+
+func (s *Store) load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	bytes, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var st state
+	if err := json.Unmarshal(bytes, &st); err != nil {
+		return err
+	}
+
+	for _, stored := range st.Keys {
+		key := stored.Key
+		key.Hash = stored.Hash
+		s.keys[key.Hash] = &key
+	}
+
+	for keyID, addresses := range st.Addresses {
+		s.addresses[keyID] = make(map[types.Address]struct{}, len(addresses))
+		for _, address := range addresses {
+			s.addresses[keyID][address] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+// persist writes keys to the file, must be called with mutex held
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	st := state{
+		Keys:      make([]storedKey, 0, len(s.keys)),
+		Addresses: make(map[string][]types.Address, len(s.addresses)),
+	}
+
+	for _, key := range s.keys {
+		if key.ID != bootstrapKeyID {
+			st.Keys = append(st.Keys, storedKey{Key: *key, Hash: key.Hash})
+		}
+	}
+
+	for keyID, owned := range s.addresses {
+		for address := range owned {
+			st.Addresses[keyID] = append(st.Addresses[keyID], address)
+		}
+	}
+
+	bytes, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("failed to marshal keys: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, bytes); err != nil {
+		return fmt.Errorf("failed to write keys: %w", err)
+	}
+
+	return nil
+}
+
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	buff := make([]byte, n)
+	if _, err := rand.Read(buff); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(buff)
+}
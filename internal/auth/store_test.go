@@ -0,0 +1,204 @@
+package auth_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/auth"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+var (
+	alice = types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	bob   = types.MustParseAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+)
+
+func Test_Store(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	store, err := auth.NewStore(path, "admin-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin, ok := store.Authenticate("admin-key")
+	if !ok || !admin.Admin {
+		t.Fatalf("config admin key is not accepted, got %+v", admin)
+	}
+
+	key, plaintext, err := store.Create("tenant", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := store.Authenticate(plaintext); !ok || got.ID != key.ID || got.Admin {
+		t.Fatalf("authenticated key is not equal, want %+v, got %+v", key, got)
+	}
+
+	if _, ok := store.Authenticate(plaintext + "x"); ok {
+		t.Fatalf("invalid key must not be accepted")
+	}
+
+	granted, err := store.Grant(key.ID, alice, 0)
+	if err != nil || !granted {
+		t.Fatalf("address is not granted, err %v", err)
+	}
+
+	if granted, _ := store.Grant(key.ID, alice, 0); granted {
+		t.Fatalf("owned address must not be granted twice")
+	}
+
+	if !store.Owns(key.ID, alice) || store.Owns(key.ID, bob) || store.Owns(admin.ID, alice) {
+		t.Fatalf("ownership is not equal, want only %s owned by %s", alice, key.ID)
+	}
+
+	// keys and addresses survive restart, config admin key is never persisted
+	reloaded, err := auth.NewStore(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := reloaded.Authenticate(plaintext); !ok {
+		t.Fatalf("persisted key is not accepted")
+	}
+
+	if _, ok := reloaded.Authenticate("admin-key"); ok {
+		t.Fatalf("config admin key must not be persisted")
+	}
+
+	if !reloaded.Owns(key.ID, alice) {
+		t.Fatalf("persisted address is not owned")
+	}
+
+	revoked, err := reloaded.Revoke(key.ID)
+	if err != nil || !revoked {
+		t.Fatalf("key is not revoked, err %v", err)
+	}
+
+	if _, ok := reloaded.Authenticate(plaintext); ok {
+		t.Fatalf("revoked key must not be accepted")
+	}
+
+	if reloaded.Owns(key.ID, alice) {
+		t.Fatalf("addresses of revoked key must be deleted")
+	}
+
+	if revoked, _ := reloaded.Revoke(key.ID); revoked {
+		t.Fatalf("unknown key must not be revoked")
+	}
+}
+
+func Test_StorePersistFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := auth.NewStore(filepath.Join(dir, "keys.json"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, plaintext, err := store.Create("tenant", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replacing directory with a file fails every following write
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Grant(key.ID, alice, 0); err == nil {
+		t.Fatalf("grant must fail")
+	}
+
+	if _, err := store.GrantAll(key.ID, []types.Address{alice, bob}, 0); err == nil {
+		t.Fatalf("grant of many addresses must fail")
+	}
+
+	if store.Owns(key.ID, alice) || store.Owns(key.ID, bob) || store.Count(key.ID) != 0 {
+		t.Fatalf("addresses of failed grant must be rolled back, got %d", store.Count(key.ID))
+	}
+
+	if _, err := store.Revoke(key.ID); err == nil {
+		t.Fatalf("revoke must fail")
+	}
+
+	if _, ok := store.Authenticate(plaintext); !ok {
+		t.Fatalf("key of failed revoke must be kept")
+	}
+}
+
+func Test_StoreQuota(t *testing.T) {
+	carol := types.MustParseAddress("0x22a7a914cf352f7361c199188a23da94fe71b277")
+
+	testCases := []struct {
+		desc      string
+		owned     []types.Address
+		addresses []types.Address
+		limit     int
+		want      []auth.GrantStatus
+	}{
+		{
+			desc:      "Unlimited",
+			addresses: []types.Address{alice, bob, carol},
+			want:      []auth.GrantStatus{auth.Granted, auth.Granted, auth.Granted},
+		},
+		{
+			desc:      "Granted in order until limit",
+			addresses: []types.Address{alice, bob, carol},
+			limit:     2,
+			want:      []auth.GrantStatus{auth.Granted, auth.Granted, auth.QuotaExceeded},
+		},
+		{
+			desc:      "Owned address is not counted again",
+			owned:     []types.Address{alice},
+			addresses: []types.Address{alice, bob, carol},
+			limit:     2,
+			want:      []auth.GrantStatus{auth.Owned, auth.Granted, auth.QuotaExceeded},
+		},
+		{
+			desc:      "Limit reached",
+			owned:     []types.Address{alice, bob},
+			addresses: []types.Address{bob, carol},
+			limit:     2,
+			want:      []auth.GrantStatus{auth.Owned, auth.QuotaExceeded},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			store, err := auth.NewStore("", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, address := range tC.owned {
+				if _, err := store.Grant("key", address, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := store.GrantAll("key", tC.addresses, tC.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(got, tC.want) {
+				t.Fatalf("statuses are not equal, want %v, got %v", tC.want, got)
+			}
+
+			// the same limit is applied to a single address
+			_, err = store.Grant("key", types.MustParseAddress("0xfe556e4f848c82093d0a33cc41761d18f67099ca"), tC.limit)
+			if wantErr := tC.limit > 0; errors.Is(err, auth.ErrQuotaExceeded) != wantErr {
+				t.Fatalf("quota error is not equal, want %v, got %v", wantErr, err)
+			}
+		})
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"crypto/rand"
 	"fmt"
 	"math/big"
+	"net/url"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/avelex/blockchain-parser/internal/jsonrpc"
 )
 
 const (
+	chainIDMethod            = "eth_chainId"
 	blockNumberMethod        = "eth_blockNumber"
 	blockByNumberMethod      = "eth_getBlockByNumber"
 	transactionReceiptMethod = "eth_getTransactionReceipt"
@@ -18,38 +22,63 @@ const (
 
 // NOTE: add rate-limit
 type Client struct {
-	id  string
-	url string
+	id string
+	// replaced on config reload, e.g. when API key is rotated
+	url *atomic.Pointer[string]
 	rpc *jsonrpc.Client
 }
 
 func New(url string) *Client {
-	return &Client{
+	return NewWithRPC(url, jsonrpc.NewClient())
+}
+
+// NewWithRPC creates client calling url with rpc client, e.g. one with replaying transport in tests
+func NewWithRPC(url string, rpc *jsonrpc.Client) *Client {
+	c := &Client{
 		id:  randomID(),
-		url: url,
-		rpc: jsonrpc.NewClient(),
+		url: &atomic.Pointer[string]{},
+		rpc: rpc,
 	}
+	c.url.Store(&url)
+
+	return c
 }
 
-func (c *Client) BlockNumber(ctx context.Context) (int, error) {
-	req := jsonrpc.NewEmptyRequest(blockNumberMethod, c.id)
+// SetURL switches client to another RPC url, calls in flight complete with the previous one
+func (c *Client) SetURL(url string) {
+	c.url.Store(&url)
+}
 
-	resp, err := c.rpc.Call(ctx, c.url, req)
+func (c *Client) ChainID(ctx context.Context) (uint64, error) {
+	req := jsonrpc.NewEmptyRequest(chainIDMethod, c.id)
+
+	resp, err := c.rpc.Call(ctx, *c.url.Load(), req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to call %s: %w", blockNumberMethod, err)
+		return 0, fmt.Errorf("failed to call %s: %w", chainIDMethod, err)
 	}
 
-	blockHex, ok := resp.Result.(string)
-	if !ok {
-		return 0, fmt.Errorf("failed to parse %s response", blockNumberMethod)
+	var chainID Quantity
+	if err := resp.DecodeResult(&chainID); err != nil {
+		return 0, fmt.Errorf("failed to parse %s response: %w", chainIDMethod, err)
 	}
 
-	blockInt, err := parseHexInt(blockHex)
+	return uint64(chainID), nil
+}
+
+func (c *Client) BlockNumber(ctx context.Context) (int, error) {
+	req := jsonrpc.NewEmptyRequest(blockNumberMethod, c.id)
+
+	resp, err := c.rpc.Call(ctx, *c.url.Load(), req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse %s block number", blockNumberMethod)
+		return 0, fmt.Errorf("failed to call %s: %w", blockNumberMethod, err)
+	}
+
+	var number Quantity
+	if err := resp.DecodeResult(&number); err != nil {
+		return 0, fmt.Errorf("failed to parse %s response: %w", blockNumberMethod, err)
 	}
 
-	return int(blockInt), nil
+	return int(number), nil
 }
 
 func (c *Client) BlockHeaderByNumber(ctx context.Context, number int) (*BlockHeader, error) {
@@ -61,33 +90,47 @@ func (c *Client) BlockHeaderByNumber(ctx context.Context, number int) (*BlockHea
 
 	req := jsonrpc.NewRequest(blockByNumberMethod, params, c.id)
 
-	resp, err := c.rpc.Call(ctx, c.url, req)
+	resp, err := c.rpc.Call(ctx, *c.url.Load(), req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call %s: %w", blockByNumberMethod, err)
 	}
 
-	header, err := blockHeaderFromResponse(resp)
-	if err != nil {
+	var header BlockHeader
+	if err := resp.DecodeResult(&header); err != nil {
 		return nil, fmt.Errorf("failed to parse %s response: %w", blockByNumberMethod, err)
 	}
 
-	return header, nil
+	return &header, nil
 }
 
 func (c *Client) TransactionReceipt(ctx context.Context, hash string) (*TransactionReceipt, error) {
 	req := jsonrpc.NewRequest(transactionReceiptMethod, []any{hash}, c.id)
 
-	resp, err := c.rpc.Call(ctx, c.url, req)
+	resp, err := c.rpc.Call(ctx, *c.url.Load(), req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call %s: %w", transactionReceiptMethod, err)
 	}
 
-	receipt, err := transactionReceiptFromResponse(resp)
-	if err != nil {
+	var receipt TransactionReceipt
+	if err := resp.DecodeResult(&receipt); err != nil {
 		return nil, fmt.Errorf("failed to parse %s response: %w", transactionReceiptMethod, err)
 	}
 
-	return receipt, nil
+	return &receipt, nil
+}
+
+// Endpoint returns scheme and host of RPC url, path and credentials are omitted as they may contain API keys
+func (c *Client) Endpoint() string {
+	u, err := url.Parse(*c.url.Load())
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// LastSuccess returns time of the last successful RPC call
+func (c *Client) LastSuccess() time.Time {
+	return c.rpc.LastSuccess()
 }
 
 func randomID() string {
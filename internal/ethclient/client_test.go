@@ -1,55 +1,135 @@
 package ethclient_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/jsonrpc"
+	"github.com/avelex/blockchain-parser/internal/simnode"
+	"github.com/avelex/blockchain-parser/internal/types"
 )
 
-var testBlockchainRPC = "https://eth.llamarpc.com"
+const (
+	testChainID = 1
+	testGenesis = 21543900
+)
+
+const testTxHash = "0xa095ab2eadeb8451e5eadc2329c8dbcabfae81bfd0b05d2f7c7fa635889b959b"
+
+var (
+	testFrom = types.MustParseAddress("0xfe556e4f848c82093d0a33cc41761d18f67099ca")
+	testTo   = types.MustParseAddress("0x22a7a914cf352f7361c199188a23da94fe71b277")
+)
+
+// newTestNode starts simulated node with block 21543920 containing 215 transactions
+func newTestNode(t *testing.T) (*simnode.Node, *ethclient.Client) {
+	t.Helper()
+
+	node := simnode.New(testChainID, testGenesis)
+	node.MineEmpty(19)
+
+	txs := make([]simnode.Transaction, 215)
+	for i := range txs {
+		txs[i] = simnode.Transaction{From: testFrom, To: testTo}
+	}
+	txs[0].Hash = testTxHash
+	txs[1] = simnode.Transaction{Hash: "0x01", From: testFrom, To: testTo, Failed: true}
+	txs[2] = simnode.Transaction{Hash: "0x02", From: testFrom}
+
+	node.Mine(txs...)
+	node.MineEmpty(5)
+
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+
+	return node, ethclient.New(server.URL)
+}
+
+func Test_ChainID(t *testing.T) {
+	_, client := newTestNode(t)
+
+	chainID, err := client.ChainID(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get chain id: %v", err)
+	}
+
+	if chainID != testChainID {
+		t.Fatalf("chain id is not equal, want %d, got %d", testChainID, chainID)
+	}
+}
 
 func Test_BlockNumber(t *testing.T) {
-	client := ethclient.New(testBlockchainRPC)
+	node, client := newTestNode(t)
 
 	testCases := []struct {
 		desc string
+		mine int
 	}{
 		{
 			desc: "Latest Block Number",
 		},
+		{
+			desc: "After New Blocks",
+			mine: 3,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
+			node.MineEmpty(tC.mine)
+
 			bn, err := client.BlockNumber(context.Background())
 			if err != nil {
 				t.Fatalf("failed to get block number: %v", err)
 			}
 
-			if bn == 0 {
-				t.Fatalf("block number is empty")
+			if bn != node.Head().Number {
+				t.Fatalf("block number is not equal, want %d, got %d", node.Head().Number, bn)
 			}
 		})
 	}
 }
 
 func Test_HeaderByNumber(t *testing.T) {
-	client := ethclient.New(testBlockchainRPC)
+	node, client := newTestNode(t)
 
 	testCases := []struct {
-		desc   string
-		number int
-		wantTx int
+		desc    string
+		number  int
+		wantTx  int
+		wantErr bool
 	}{
 		{
 			desc:   "Existing Block",
 			number: 21543920,
 			wantTx: 215,
 		},
+		{
+			desc:   "Empty Block",
+			number: 21543921,
+		},
+		{
+			desc:    "Future Block",
+			number:  21600000,
+			wantErr: true,
+		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			block, err := client.BlockHeaderByNumber(context.Background(), tC.number)
+			if tC.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for block %d", tC.number)
+				}
+				return
+			}
+
 			if err != nil {
 				t.Fatalf("failed to get block number: %v", err)
 			}
@@ -58,31 +138,68 @@ func Test_HeaderByNumber(t *testing.T) {
 				t.Fatalf("block is empty")
 			}
 
-			if block.Number != tC.number {
+			if len(block.Transactions) != tC.wantTx {
+				t.Fatalf("block transactions count is not equal to %d", tC.wantTx)
+			}
+
+			if int(block.Number) != tC.number {
 				t.Fatalf("block number is not equal to %d", tC.number)
 			}
+
+			want, _ := node.Block(tC.number)
+			parent, _ := node.Block(tC.number - 1)
+
+			if string(block.Hash) != want.Hash || string(block.ParentHash) != parent.Hash {
+				t.Fatalf("block hashes are not equal to node ones")
+			}
 		})
 	}
 }
 
 func Test_TransactionReceipt(t *testing.T) {
-	client := ethclient.New(testBlockchainRPC)
+	_, client := newTestNode(t)
 
 	testCases := []struct {
 		desc             string
 		txHash           string
-		wantFrom, wantTo string
+		wantFrom, wantTo types.Address
+		wantFailed       bool
+		wantErr          bool
 	}{
 		{
 			desc:     "Existing Transaction",
-			txHash:   "0xa095ab2eadeb8451e5eadc2329c8dbcabfae81bfd0b05d2f7c7fa635889b959b",
-			wantFrom: "0xfe556e4f848c82093d0a33cc41761d18f67099ca",
-			wantTo:   "0x22a7a914cf352f7361c199188a23da94fe71b277",
+			txHash:   testTxHash,
+			wantFrom: testFrom,
+			wantTo:   testTo,
+		},
+		{
+			desc:       "Reverted Transaction",
+			txHash:     "0x01",
+			wantFrom:   testFrom,
+			wantTo:     testTo,
+			wantFailed: true,
+		},
+		{
+			desc:     "Contract Creation",
+			txHash:   "0x02",
+			wantFrom: testFrom,
+		},
+		{
+			desc:    "Unknown Transaction",
+			txHash:  "0x03",
+			wantErr: true,
 		},
 	}
 	for _, tC := range testCases {
 		t.Run(tC.desc, func(t *testing.T) {
 			receipt, err := client.TransactionReceipt(context.Background(), tC.txHash)
+			if tC.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for transaction %s", tC.txHash)
+				}
+				return
+			}
+
 			if err != nil {
 				t.Fatalf("failed to get transaction receipt: %v", err)
 			}
@@ -91,7 +208,7 @@ func Test_TransactionReceipt(t *testing.T) {
 				t.Fatalf("transaction receipt is empty")
 			}
 
-			if receipt.Hash != tC.txHash {
+			if string(receipt.Hash) != tC.txHash {
 				t.Fatalf("transaction hash is not equal, want %s, got %s", tC.txHash, receipt.Hash)
 			}
 
@@ -102,6 +219,136 @@ func Test_TransactionReceipt(t *testing.T) {
 			if receipt.To != tC.wantTo {
 				t.Fatalf("transaction to is not equal, want %s, got %s", tC.wantTo, receipt.To)
 			}
+
+			if receipt.IsFailed() != tC.wantFailed {
+				t.Fatalf("transaction failed is not equal, want %v, got %v", tC.wantFailed, receipt.IsFailed())
+			}
 		})
 	}
 }
+
+func Test_NodeFailures(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		inject  func(node *simnode.Node)
+		timeout time.Duration
+		code    int
+	}{
+		{
+			desc:   "Internal Error",
+			inject: func(node *simnode.Node) { node.FailNext("eth_blockNumber", 1) },
+			code:   -32603,
+		},
+		{
+			desc:   "Rate Limit",
+			inject: func(node *simnode.Node) { node.SetRateLimit(1) },
+			code:   -32005,
+		},
+		{
+			desc:    "Latency Over Timeout",
+			inject:  func(node *simnode.Node) { node.SetLatency(200 * time.Millisecond) },
+			timeout: 50 * time.Millisecond,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			node, client := newTestNode(t)
+
+			tC.inject(node)
+
+			// warm up call is within rate limit and has no timeout
+			if _, err := client.ChainID(context.Background()); err != nil {
+				t.Fatalf("failed to get chain id: %v", err)
+			}
+
+			ctx := context.Background()
+			if tC.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tC.timeout)
+				defer cancel()
+			}
+
+			_, err := client.BlockNumber(ctx)
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+
+			var rpcErr *jsonrpc.ResponseError
+			if tC.code != 0 && (!errors.As(err, &rpcErr) || rpcErr.Code != tC.code) {
+				t.Fatalf("expected JSON-RPC error code %d, got %v", tC.code, err)
+			}
+
+			if tC.timeout > 0 && !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected deadline exceeded, got %v", err)
+			}
+
+			// failures are not sticky
+			node.SetRateLimit(0)
+			node.SetLatency(0)
+
+			if _, err := client.BlockNumber(context.Background()); err != nil {
+				t.Fatalf("failed to get block number after failure: %v", err)
+			}
+		})
+	}
+}
+
+// largeBlockTxs is close to transactions count of the biggest mainnet blocks
+const largeBlockTxs = 1500
+
+// newStaticServer serves node response to request recorded once,
+// so benchmarks count only client allocations
+func newStaticServer(b *testing.B, node *simnode.Node, request jsonrpc.Request) *httptest.Server {
+	b.Helper()
+
+	payload, err := request.JSON()
+	if err != nil {
+		b.Fatalf("failed to marshal request: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	node.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload)))
+	body := recorder.Body.Bytes()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	b.Cleanup(server.Close)
+
+	return server
+}
+
+func Benchmark_BlockHeaderByNumber(b *testing.B) {
+	node := simnode.New(testChainID, testGenesis)
+	block := node.Mine(make([]simnode.Transaction, largeBlockTxs)...)
+
+	server := newStaticServer(b, node, jsonrpc.NewRequest("eth_getBlockByNumber", []any{fmt.Sprintf("0x%x", block.Number), false}))
+	client := ethclient.New(server.URL)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := client.BlockHeaderByNumber(context.Background(), block.Number); err != nil {
+			b.Fatalf("failed to get block header: %v", err)
+		}
+	}
+}
+
+func Benchmark_TransactionReceipt(b *testing.B) {
+	node := simnode.New(testChainID, testGenesis)
+	node.Mine(simnode.Transaction{Hash: testTxHash, From: testFrom, To: testTo})
+
+	server := newStaticServer(b, node, jsonrpc.NewRequest("eth_getTransactionReceipt", []any{testTxHash}))
+	client := ethclient.New(server.URL)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := client.TransactionReceipt(context.Background(), testTxHash); err != nil {
+			b.Fatalf("failed to get receipt: %v", err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package ethclient
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	ErrInvalidQuantity = errors.New("invalid hex quantity")
+	ErrInvalidData     = errors.New("invalid hex data")
+)
+
+// Quantity is 0x prefixed hex encoded integer, e.g. block number or timestamp.
+// Null leaves the value untouched.
+type Quantity uint64
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(`"0x` + strconv.FormatUint(uint64(q), 16) + `"`), nil
+}
+
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	digits, ok := hexString(data)
+	if !ok || len(digits) == 0 || len(digits) > 16 {
+		return fmt.Errorf("%w: %s", ErrInvalidQuantity, data)
+	}
+
+	var v uint64
+	for _, c := range digits {
+		v = v<<4 | uint64(hexDigit(c))
+	}
+
+	*q = Quantity(v)
+	return nil
+}
+
+// Data is 0x prefixed hex encoded bytes, e.g. hash.
+// It is kept encoded, as hashes are passed to RPC and API as strings.
+type Data string
+
+func (d *Data) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	digits, ok := hexString(data)
+	if !ok || len(digits)%2 != 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidData, data)
+	}
+
+	*d = Data(data[1 : len(data)-1])
+	return nil
+}
+
+// hexString returns hex digits of quoted 0x prefixed JSON string
+func hexString(data []byte) ([]byte, bool) {
+	if len(data) < 4 || data[0] != '"' || data[len(data)-1] != '"' || data[1] != '0' || (data[2] != 'x' && data[2] != 'X') {
+		return nil, false
+	}
+
+	digits := data[3 : len(data)-1]
+	for _, c := range digits {
+		if hexDigit(c) < 0 {
+			return nil, false
+		}
+	}
+
+	return digits, true
+}
+
+// hexValues maps hex digits to their values and other bytes to -1, large blocks have thousands of hashes to check
+var hexValues = func() (t [256]int8) {
+	for i := range t {
+		t[i] = -1
+	}
+	for c := '0'; c <= '9'; c++ {
+		t[c] = int8(c - '0')
+	}
+	for c := 'a'; c <= 'f'; c++ {
+		t[c] = int8(c-'a') + 10
+		t[c-'a'+'A'] = int8(c-'a') + 10
+	}
+	return t
+}()
+
+func hexDigit(c byte) int8 {
+	return hexValues[c]
+}
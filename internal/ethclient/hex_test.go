@@ -0,0 +1,82 @@
+package ethclient_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+)
+
+func Test_DecodeBlockHeader(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		json    string
+		want    ethclient.BlockHeader
+		wantErr error
+	}{
+		{
+			desc: "Full Header",
+			json: `{"number":"0x148bf43","hash":"0xab","parentHash":"0xCD","timestamp":"0x6553f100","transactions":["0x01","0x02"],"miner":"0x00"}`,
+			want: ethclient.BlockHeader{
+				Number:       21544771,
+				Hash:         "0xab",
+				ParentHash:   "0xCD",
+				Timestamp:    1700000000,
+				Transactions: []ethclient.Data{"0x01", "0x02"},
+			},
+		},
+		{
+			desc: "Null Fields",
+			json: `{"number":"0x0","hash":null,"parentHash":null,"timestamp":null,"transactions":null}`,
+			want: ethclient.BlockHeader{},
+		},
+		{
+			desc:    "Quantity Without Prefix",
+			json:    `{"number":"148bf43"}`,
+			wantErr: ethclient.ErrInvalidQuantity,
+		},
+		{
+			desc:    "Quantity Overflow",
+			json:    `{"number":"0x10000000000000000"}`,
+			wantErr: ethclient.ErrInvalidQuantity,
+		},
+		{
+			desc:    "Quantity As Number",
+			json:    `{"timestamp":1700000000}`,
+			wantErr: ethclient.ErrInvalidQuantity,
+		},
+		{
+			desc:    "Odd Data Length",
+			json:    `{"hash":"0xabc"}`,
+			wantErr: ethclient.ErrInvalidData,
+		},
+		{
+			desc:    "Not Hex Data",
+			json:    `{"transactions":["0xzz"]}`,
+			wantErr: ethclient.ErrInvalidData,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var got ethclient.BlockHeader
+			err := json.Unmarshal([]byte(tC.json), &got)
+
+			if tC.wantErr != nil {
+				if !errors.Is(err, tC.wantErr) {
+					t.Fatalf("error is not equal, want %v, got %v", tC.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to decode header: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tC.want) {
+				t.Fatalf("header is not equal, want %+v, got %+v", tC.want, got)
+			}
+		})
+	}
+}
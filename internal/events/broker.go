@@ -0,0 +1,117 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+type Type string
+
+const (
+	// TypeTransaction is published when a transaction of a subscribed address is found in a processed block
+	TypeTransaction Type = "transaction"
+	// TypeConfirmation is published when a block with a subscribed transaction reaches required confirmations
+	TypeConfirmation Type = "confirmation"
+	// TypeReorg is published when a processed block was replaced in the canonical chain
+	TypeReorg Type = "reorg"
+)
+
+type Event struct {
+	Type        Type               `json:"type"`
+	ChainID     uint64             `json:"chain_id"`
+	Address     types.Address      `json:"address,omitempty"`
+	Block       int                `json:"block"`
+	Transaction *types.Transaction `json:"transaction,omitempty"`
+}
+
+// MarshalJSON omits zero address of events not related to address, e.g. reorg
+func (e Event) MarshalJSON() ([]byte, error) {
+	var address *types.Address
+	if !e.Address.IsZero() {
+		address = &e.Address
+	}
+
+	return json.Marshal(struct {
+		Type        Type               `json:"type"`
+		ChainID     uint64             `json:"chain_id"`
+		Address     *types.Address     `json:"address,omitempty"`
+		Block       int                `json:"block"`
+		Transaction *types.Transaction `json:"transaction,omitempty"`
+	}{e.Type, e.ChainID, address, e.Block, e.Transaction})
+}
+
+// Broker fans out parser events to subscribers.
+// Subscribers that can't keep up with published events are dropped and their channel is closed.
+type Broker struct {
+	mu   *sync.Mutex
+	subs map[chan Event]struct{}
+	// handlers never miss events, they are called by publisher
+	handlers []func(Event)
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		mu:   &sync.Mutex{},
+		subs: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns channel with events and function to cancel subscription
+func (b *Broker) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.remove(ch)
+	}
+
+	return ch, cancel
+}
+
+// Handle registers fn called synchronously by Publish for every event. Unlike channel subscribers
+// it's never dropped, so it's used by consumers which must not lose events, e.g. webhook outbox.
+func (b *Broker) Handle(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, fn)
+}
+
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	handlers := b.handlers
+	b.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(e)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			// slow consumer
+			b.remove(ch)
+		}
+	}
+}
+
+func (b *Broker) remove(ch chan Event) {
+	if _, ok := b.subs[ch]; !ok {
+		return
+	}
+
+	delete(b.subs, ch)
+	close(ch)
+}
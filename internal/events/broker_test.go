@@ -0,0 +1,91 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/events"
+)
+
+func Test_Broker(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		buffers   []int
+		published int
+		// events received by subscriber, -1 if subscriber is removed
+		want []int
+	}{
+		{
+			desc:      "Fan out to every subscriber",
+			buffers:   []int{4, 4, 4},
+			published: 3,
+			want:      []int{3, 3, 3},
+		},
+		{
+			desc:      "Slow consumer is removed",
+			buffers:   []int{1, 4},
+			published: 3,
+			want:      []int{-1, 3},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			broker := events.NewBroker()
+
+			subs := make([]<-chan events.Event, len(tC.buffers))
+			for i, buffer := range tC.buffers {
+				ch, cancel := broker.Subscribe(buffer)
+				defer cancel()
+				subs[i] = ch
+			}
+
+			for i := 0; i < tC.published; i++ {
+				broker.Publish(events.Event{Type: events.TypeTransaction, Block: i})
+			}
+
+			for i, ch := range subs {
+				got := 0
+				closed := false
+
+			drain:
+				for {
+					select {
+					case e, ok := <-ch:
+						if !ok {
+							closed = true
+							break drain
+						}
+						if e.Block != got {
+							t.Fatalf("event is out of order, want block %d, got %d", got, e.Block)
+						}
+						got++
+					default:
+						break drain
+					}
+				}
+
+				if closed {
+					got = -1
+				}
+
+				if got != tC.want[i] {
+					t.Fatalf("received events of subscriber %d are not equal, want %d, got %d", i, tC.want[i], got)
+				}
+			}
+		})
+	}
+}
+
+func Test_BrokerCancel(t *testing.T) {
+	broker := events.NewBroker()
+
+	ch, cancel := broker.Subscribe(1)
+	cancel()
+	// cancel is idempotent, e.g. after slow consumer removal
+	cancel()
+
+	broker.Publish(events.Event{Type: events.TypeReorg})
+
+	if _, ok := <-ch; ok {
+		t.Fatalf("channel of canceled subscription must be closed")
+	}
+}
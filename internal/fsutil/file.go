@@ -0,0 +1,49 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to temporary file and renames it, so the file is never partially written
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	return nil
+}
+
+// AppendLine writes data with trailing newline to file opened for appending. Failed or short write is truncated,
+// so the file never has incomplete line followed by complete ones.
+func AppendLine(f *os.File, data []byte) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		if truncErr := f.Truncate(info.Size()); truncErr != nil {
+			return fmt.Errorf("failed to write line: %w, failed to truncate it: %w", err, truncErr)
+		}
+		return fmt.Errorf("failed to write line: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,6 @@
+package fsutil
+
+import "errors"
+
+// ErrLocked is returned by Lock if the file is locked by another process
+var ErrLocked = errors.New("file is locked by another process")
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package fsutil
+
+import (
+	"fmt"
+	"os"
+)
+
+// Lock creates file at path, locking is not supported on this platform
+func Lock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	return f, nil
+}
@@ -5,19 +5,64 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/metrics"
+)
+
+var (
+	requestDuration = metrics.NewHistogramVec("jsonrpc_request_duration_seconds", "Duration of JSON-RPC calls by method.", metrics.DefaultBuckets, "method")
+	requestErrors   = metrics.NewCounterVec("jsonrpc_request_errors_total", "Number of failed JSON-RPC calls by method.", "method")
 )
 
 type Client struct {
 	c *http.Client
+
+	// unix nano time of the last successful call
+	lastSuccess *atomic.Int64
 }
 
 func NewClient() *Client {
 	return &Client{
-		c: http.DefaultClient,
+		c:           http.DefaultClient,
+		lastSuccess: &atomic.Int64{},
+	}
+}
+
+// NewClientWithTransport creates client sending requests with transport, e.g. recording or replaying one
+func NewClientWithTransport(transport http.RoundTripper) *Client {
+	return &Client{
+		c:           &http.Client{Transport: transport},
+		lastSuccess: &atomic.Int64{},
 	}
 }
 
 func (c *Client) Call(ctx context.Context, url string, request Request) (Response, error) {
+	start := time.Now()
+
+	response, err := c.call(ctx, url, request)
+
+	requestDuration.WithLabelValues(request.Method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		requestErrors.WithLabelValues(request.Method).Inc()
+	} else {
+		c.lastSuccess.Store(time.Now().UnixNano())
+	}
+
+	return response, err
+}
+
+// LastSuccess returns time of the last successful call, zero if there were none
+func (c *Client) LastSuccess() time.Time {
+	nano := c.lastSuccess.Load()
+	if nano == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nano)
+}
+
+func (c *Client) call(ctx context.Context, url string, request Request) (Response, error) {
 	payload, err := request.JSON()
 	if err != nil {
 		return Response{}, err
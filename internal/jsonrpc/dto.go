@@ -1,12 +1,17 @@
 package jsonrpc
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
 const version = "2.0"
 
+// ErrNullResult is returned for null result, e.g. for unknown block or transaction
+var ErrNullResult = errors.New("result is null")
+
 type Request struct {
 	Version string `json:"jsonrpc"`
 	Method  string `json:"method"`
@@ -37,10 +42,19 @@ func (r Request) JSON() ([]byte, error) {
 }
 
 type Response struct {
-	ID      string         `json:"id"`
-	Version string         `json:"jsonrpc"`
-	Result  any            `json:"result,omitempty"`
-	Error   *ResponseError `json:"error,omitempty"`
+	ID      string          `json:"id"`
+	Version string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// DecodeResult decodes result into v, null result is an error
+func (r Response) DecodeResult(v any) error {
+	if len(r.Result) == 0 || bytes.Equal(r.Result, []byte("null")) {
+		return ErrNullResult
+	}
+
+	return json.Unmarshal(r.Result, v)
 }
 
 type ResponseError struct {
@@ -0,0 +1,244 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Exchange is a request and response pair, stored in fixture files as a JSON line
+type Exchange struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// RecordingTransport passes requests to the next transport and appends successful exchanges to w
+type RecordingTransport struct {
+	mu   *sync.Mutex
+	next http.RoundTripper
+	w    io.Writer
+}
+
+func NewRecordingTransport(next http.RoundTripper, w io.Writer) *RecordingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &RecordingTransport{
+		mu:   &sync.Mutex{},
+		next: next,
+		w:    w,
+	}
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// rate limits and other transient failures are not recorded
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	if err := t.record(reqBody, respBody); err != nil {
+		return nil, fmt.Errorf("failed to record exchange: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (t *RecordingTransport) record(reqBody, respBody []byte) error {
+	var e Exchange
+
+	if err := compact(&e.Request, reqBody); err != nil {
+		return err
+	}
+
+	if err := compact(&e.Response, respBody); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err = t.w.Write(append(line, '\n'))
+	return err
+}
+
+// ReplayTransport serves recorded responses without network access.
+// Requests are matched by method and params, repeated requests get recorded responses in order
+// and the last one when they run out. Unknown requests fail.
+type ReplayTransport struct {
+	mu        *sync.Mutex
+	responses map[string][]json.RawMessage
+	served    map[string]int
+}
+
+func NewReplayTransport(r io.Reader) (*ReplayTransport, error) {
+	t := &ReplayTransport{
+		mu:        &sync.Mutex{},
+		responses: make(map[string][]json.RawMessage),
+		served:    make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(r)
+	// blocks with full transactions don't fit default buffer
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var e Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", line, err)
+		}
+
+		key, _, err := exchangeKey(e.Request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse request on line %d: %w", line, err)
+		}
+
+		t.responses[key] = append(t.responses[key], e.Response)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	return t, nil
+}
+
+// LoadReplayTransport reads fixture file recorded by RecordingTransport
+func LoadReplayTransport(path string) (*ReplayTransport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixture: %w", err)
+	}
+	defer f.Close()
+
+	return NewReplayTransport(f)
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+
+	key, id, err := exchangeKey(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+
+	t.mu.Lock()
+	responses := t.responses[key]
+	i := min(t.served[key], len(responses)-1)
+	t.served[key]++
+	t.mu.Unlock()
+
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s", key)
+	}
+
+	body, err := withID(responses[i], id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replace response id: %w", err)
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// exchangeKey identifies request by method and params, ids differ between runs
+func exchangeKey(request []byte) (string, json.RawMessage, error) {
+	var r struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params any             `json:"params"`
+	}
+
+	if err := json.Unmarshal(request, &r); err != nil {
+		return "", nil, err
+	}
+
+	// marshalling decoded params sorts object keys
+	params, err := json.Marshal(r.Params)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return r.Method + " " + string(params), r.ID, nil
+}
+
+func withID(response, id json.RawMessage) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(response, &fields); err != nil {
+		return nil, err
+	}
+
+	if id != nil {
+		fields["id"] = id
+	}
+
+	return json.Marshal(fields)
+}
+
+// readBody reads body and replaces it with a copy, so it can be read again
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = io.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+func compact(dst *json.RawMessage, src []byte) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, src); err != nil {
+		return err
+	}
+
+	*dst = buf.Bytes()
+	return nil
+}
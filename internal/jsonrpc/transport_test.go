@@ -0,0 +1,96 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/jsonrpc"
+	"github.com/avelex/blockchain-parser/internal/simnode"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+func Test_RecordAndReplay(t *testing.T) {
+	node := simnode.New(1, 100)
+	block := node.Mine(simnode.Transaction{
+		From: types.MustParseAddress("0xe93685f3bba03016f02bd1828badd6195988d950"),
+		To:   types.MustParseAddress("0x22a7a914cf352f7361c199188a23da94fe71b277"),
+	})
+
+	server := httptest.NewServer(node)
+
+	var fixture bytes.Buffer
+	recording := ethclient.NewWithRPC(server.URL, jsonrpc.NewClientWithTransport(jsonrpc.NewRecordingTransport(nil, &fixture)))
+
+	wantHeader, err := recording.BlockHeaderByNumber(context.Background(), block.Number)
+	if err != nil {
+		t.Fatalf("failed to get block header: %v", err)
+	}
+
+	wantReceipt, err := recording.TransactionReceipt(context.Background(), block.Transactions[0].Hash)
+	if err != nil {
+		t.Fatalf("failed to get receipt: %v", err)
+	}
+
+	// replay must not touch the node
+	server.Close()
+
+	if lines := strings.Count(fixture.String(), "\n"); lines != 2 {
+		t.Fatalf("recorded exchanges count is not equal to 2, got %d", lines)
+	}
+
+	transport, err := jsonrpc.NewReplayTransport(&fixture)
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+
+	replaying := ethclient.NewWithRPC(server.URL, jsonrpc.NewClientWithTransport(transport))
+
+	testCases := []struct {
+		desc    string
+		call    func() (any, error)
+		want    any
+		wantErr bool
+	}{
+		{
+			desc: "Recorded Block",
+			call: func() (any, error) { return replaying.BlockHeaderByNumber(context.Background(), block.Number) },
+			want: wantHeader,
+		},
+		{
+			desc: "Recorded Receipt",
+			call: func() (any, error) {
+				return replaying.TransactionReceipt(context.Background(), block.Transactions[0].Hash)
+			},
+			want: wantReceipt,
+		},
+		{
+			desc:    "Not Recorded Block",
+			call:    func() (any, error) { return replaying.BlockHeaderByNumber(context.Background(), block.Number+1) },
+			wantErr: true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := tC.call()
+			if tC.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for not recorded request")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to replay: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tC.want) {
+				t.Fatalf("replayed result is not equal, want %+v, got %+v", tC.want, got)
+			}
+		})
+	}
+}
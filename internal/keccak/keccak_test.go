@@ -0,0 +1,48 @@
+package keccak_test
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/keccak"
+)
+
+func Test_Sum256(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		input string
+		want  string
+	}{
+		{
+			desc:  "Empty",
+			input: "",
+			want:  "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		},
+		{
+			desc:  "Short",
+			input: "abc",
+			want:  "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+		},
+		{
+			desc:  "Transfer Event Signature",
+			input: "Transfer(address,address,uint256)",
+			want:  "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+		},
+		{
+			desc:  "Longer Than Rate",
+			input: strings.Repeat("a", 200),
+			want:  "96ea54061def936c4be90b518992fdc6f12f535068a256229aca54267b4d084d",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			sum := keccak.Sum256([]byte(tC.input))
+			got := hex.EncodeToString(sum[:])
+
+			if got != tC.want {
+				t.Fatalf("hash is not equal, want %s, got %s", tC.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Minimal implementation of Prometheus metrics with text exposition format,
+// see https://prometheus.io/docs/instrumenting/exposition_formats/
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are histogram buckets in seconds suitable for network latency
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is registry used by package level constructors
+var Default = NewRegistry()
+
+type family interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu       *sync.Mutex
+	families []family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		mu: &sync.Mutex{},
+	}
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.families {
+		if existing.name() == f.name() {
+			panic("metrics: duplicate metric " + f.name())
+		}
+	}
+
+	r.families = append(r.families, f)
+}
+
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	slices.SortFunc(families, func(a, b family) int {
+		return strings.Compare(a.name(), b.name())
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		r.Write(w)
+	})
+}
+
+type desc struct {
+	Name string
+	Help string
+	Type string
+}
+
+func (d desc) name() string {
+	return d.Name
+}
+
+func (d desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.Name, helpEscaper.Replace(d.Help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.Name, d.Type)
+}
+
+// value is float64 safe for concurrent updates
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) Add(delta float64) {
+	for {
+		old := v.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if v.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+func (v *value) Set(f float64) {
+	v.bits.Store(math.Float64bits(f))
+}
+
+func (v *value) Get() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+type Counter struct {
+	value
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+type Gauge struct {
+	value
+}
+
+type Histogram struct {
+	buckets []float64
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sum     value
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]atomic.Uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) Observe(v float64) {
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i].Add(1)
+		}
+	}
+
+	h.count.Add(1)
+	h.sum.Add(v)
+}
+
+func (h *Histogram) write(w *bufio.Writer, name, labels string) {
+	for i, upper := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, joinLabels(labels, `le="`+formatFloat(upper)+`"`), h.counts[i].Load())
+	}
+
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, joinLabels(labels, `le="+Inf"`), h.count.Load())
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, joinLabels(labels, ""), formatFloat(h.sum.Get()))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, joinLabels(labels, ""), h.count.Load())
+}
+
+type counterFamily struct {
+	desc
+	*Counter
+}
+
+func (f counterFamily) write(w *bufio.Writer) {
+	f.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", f.Name, formatFloat(f.Get()))
+}
+
+type gaugeFamily struct {
+	desc
+	*Gauge
+}
+
+func (f gaugeFamily) write(w *bufio.Writer) {
+	f.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", f.Name, formatFloat(f.Get()))
+}
+
+type histogramFamily struct {
+	desc
+	*Histogram
+}
+
+func (f histogramFamily) write(w *bufio.Writer) {
+	f.writeHeader(w)
+	f.Histogram.write(w, f.Name, "")
+}
+
+func NewCounter(name, help string) *Counter {
+	c := &Counter{}
+	Default.register(counterFamily{desc{name, help, "counter"}, c})
+	return c
+}
+
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{}
+	Default.register(gaugeFamily{desc{name, help, "gauge"}, g})
+	return g
+}
+
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := newHistogram(buckets)
+	Default.register(histogramFamily{desc{name, help, "histogram"}, h})
+	return h
+}
+
+// vec holds metrics partitioned by label values
+type vec[T any] struct {
+	desc
+	labels []string
+	create func() *T
+
+	mu      *sync.RWMutex
+	metrics map[string]*T
+}
+
+func (v *vec[T]) WithLabelValues(values ...string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.Name, len(v.labels), len(values)))
+	}
+
+	key := v.labelsString(values)
+
+	v.mu.RLock()
+	m, ok := v.metrics[key]
+	v.mu.RUnlock()
+
+	if ok {
+		return m
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if m, ok := v.metrics[key]; ok {
+		return m
+	}
+
+	m = v.create()
+	v.metrics[key] = m
+
+	return m
+}
+
+func (v *vec[T]) labelsString(values []string) string {
+	pairs := make([]string, len(values))
+	for i, value := range values {
+		pairs[i] = v.labels[i] + `="` + labelEscaper.Replace(value) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v *vec[T]) each(fn func(labels string, m *T)) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	keys := make([]string, 0, len(v.metrics))
+	for key := range v.metrics {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		fn(key, v.metrics[key])
+	}
+}
+
+type CounterVec struct {
+	*vec[Counter]
+}
+
+func (c CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w)
+	c.each(func(labels string, m *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", c.Name, joinLabels(labels, ""), formatFloat(m.Get()))
+	})
+}
+
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+func (g GaugeVec) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	g.each(func(labels string, m *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", g.Name, joinLabels(labels, ""), formatFloat(m.Get()))
+	})
+}
+
+type HistogramVec struct {
+	*vec[Histogram]
+}
+
+func (h HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	h.each(func(labels string, m *Histogram) {
+		m.write(w, h.Name, labels)
+	})
+}
+
+func NewCounterVec(name, help string, labels ...string) CounterVec {
+	c := CounterVec{newVec(desc{name, help, "counter"}, labels, func() *Counter { return &Counter{} })}
+	Default.register(c)
+	return c
+}
+
+func NewGaugeVec(name, help string, labels ...string) GaugeVec {
+	g := GaugeVec{newVec(desc{name, help, "gauge"}, labels, func() *Gauge { return &Gauge{} })}
+	Default.register(g)
+	return g
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) HistogramVec {
+	h := HistogramVec{newVec(desc{name, help, "histogram"}, labels, func() *Histogram { return newHistogram(buckets) })}
+	Default.register(h)
+	return h
+}
+
+func newVec[T any](d desc, labels []string, create func() *T) *vec[T] {
+	return &vec[T]{
+		desc:    d,
+		labels:  labels,
+		create:  create,
+		mu:      &sync.RWMutex{},
+		metrics: make(map[string]*T),
+	}
+}
+
+var (
+	// HELP text escapes backslash and line feed
+	helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	// label value additionally escapes double quote
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func joinLabels(labels, extra string) string {
+	switch {
+	case labels == "" && extra == "":
+		return ""
+	case labels == "":
+		return "{" + extra + "}"
+	case extra == "":
+		return "{" + labels + "}"
+	default:
+		return "{" + labels + "," + extra + "}"
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,88 @@
+package metrics_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/metrics"
+)
+
+func Test_Write(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		name     string
+		register func(name string)
+		want     string
+	}{
+		{
+			desc: "Plain label value",
+			name: "test_plain",
+			register: func(name string) {
+				metrics.NewCounterVec(name, "Number of requests.", "method").WithLabelValues("GET").Add(2)
+			},
+			want: "# HELP test_plain Number of requests.\n" +
+				"# TYPE test_plain counter\n" +
+				"test_plain{method=\"GET\"} 2\n",
+		},
+		{
+			desc: "Label value with quote, backslash and line feed",
+			name: "test_label_escape",
+			register: func(name string) {
+				metrics.NewGaugeVec(name, "Gauge.", "path", "note").WithLabelValues(`C:\dir`, "say \"hi\"\nbye").Set(1)
+			},
+			want: "# HELP test_label_escape Gauge.\n" +
+				"# TYPE test_label_escape gauge\n" +
+				`test_label_escape{path="C:\\dir",note="say \"hi\"\nbye"} 1` + "\n",
+		},
+		{
+			desc: "Help with backslash and line feed, quote is kept",
+			name: "test_help_escape",
+			register: func(name string) {
+				metrics.NewCounter(name, "Path \\ of \"file\"\nsecond line.").Inc()
+			},
+			want: "# HELP test_help_escape Path \\\\ of \"file\"\\nsecond line.\n" +
+				"# TYPE test_help_escape counter\n" +
+				"test_help_escape 1\n",
+		},
+		{
+			desc: "Histogram with escaped label value",
+			name: "test_histogram_escape",
+			register: func(name string) {
+				metrics.NewHistogramVec(name, "Latency.", []float64{1}, "rpc").WithLabelValues(`"eth"`).Observe(0.5)
+			},
+			want: "# HELP test_histogram_escape Latency.\n" +
+				"# TYPE test_histogram_escape histogram\n" +
+				`test_histogram_escape_bucket{rpc="\"eth\"",le="1"} 1` + "\n" +
+				`test_histogram_escape_bucket{rpc="\"eth\"",le="+Inf"} 1` + "\n" +
+				`test_histogram_escape_sum{rpc="\"eth\""} 0.5` + "\n" +
+				`test_histogram_escape_count{rpc="\"eth\""} 1` + "\n",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tC.register(tC.name)
+
+			buf := &bytes.Buffer{}
+			if err := metrics.Default.Write(buf); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := family(buf.String(), tC.name); got != tC.want {
+				t.Fatalf("output is not equal, want %q, got %q", tC.want, got)
+			}
+		})
+	}
+}
+
+// family returns lines of metric family from exposition output
+func family(out, name string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if strings.HasPrefix(line, "# HELP "+name+" ") || strings.HasPrefix(line, "# TYPE "+name+" ") ||
+			strings.HasPrefix(line, name+" ") || strings.HasPrefix(line, name+"{") || strings.HasPrefix(line, name+"_") {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
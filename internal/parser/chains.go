@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// Chains is a set of parsers of different chains, the first one is the default chain.
+// Addresses are subscribed on all chains as the same address is controlled by the same key on every EVM chain.
+type Chains struct {
+	parsers []Parser
+	byID    map[uint64]Parser
+}
+
+func NewChains(parsers ...Parser) *Chains {
+	c := &Chains{
+		parsers: parsers,
+		byID:    make(map[uint64]Parser, len(parsers)),
+	}
+
+	for _, p := range parsers {
+		c.byID[p.ChainID()] = p
+	}
+
+	return c
+}
+
+func (c *Chains) Get(chainID uint64) (Parser, bool) {
+	p, ok := c.byID[chainID]
+	return p, ok
+}
+
+func (c *Chains) Default() Parser {
+	return c.parsers[0]
+}
+
+func (c *Chains) All() []Parser {
+	return c.parsers
+}
+
+// Subscribe adds address to parsers of all chains, returns false if it was already subscribed on every chain
+func (c *Chains) Subscribe(address types.Address) bool {
+	created := false
+	for _, p := range c.parsers {
+		if p.Subscribe(address) {
+			created = true
+		}
+	}
+	return created
+}
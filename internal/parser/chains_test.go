@@ -0,0 +1,73 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+)
+
+func Test_Chains(t *testing.T) {
+	repo := memory.New()
+	broker := events.NewBroker()
+	client := ethclient.New("http://127.0.0.1:0")
+
+	mainnet := parser.New(config.ChainConfig{Name: "mainnet", ChainID: 1}, client, repo, broker)
+	optimism := parser.New(config.ChainConfig{Name: "optimism", ChainID: 10}, client, repo, broker)
+
+	chains := parser.NewChains(mainnet, optimism)
+
+	if got := chains.Default(); got != mainnet {
+		t.Fatalf("default chain is not equal, want %s, got %s", mainnet.Name(), got.Name())
+	}
+
+	testCases := []struct {
+		desc    string
+		chainID uint64
+		want    parser.Parser
+	}{
+		{
+			desc:    "First chain",
+			chainID: 1,
+			want:    mainnet,
+		},
+		{
+			desc:    "Second chain",
+			chainID: 10,
+			want:    optimism,
+		},
+		{
+			desc:    "Unknown chain",
+			chainID: 137,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, ok := chains.Get(tC.chainID)
+			if ok != (tC.want != nil) || (ok && got != tC.want) {
+				t.Fatalf("chain is not equal, want %v, got %v", tC.want, got)
+			}
+		})
+	}
+
+	if !chains.Subscribe(alice) {
+		t.Fatalf("new address must be subscribed")
+	}
+
+	if mainnet.Subscribe(alice) || optimism.Subscribe(alice) {
+		t.Fatalf("address must be subscribed on all chains")
+	}
+
+	if chains.Subscribe(alice) {
+		t.Fatalf("address subscribed on every chain must not be subscribed again")
+	}
+
+	// address subscribed on a single chain is completed on the rest
+	optimism.Subscribe(bob)
+	if !chains.Subscribe(bob) || mainnet.Subscribe(bob) {
+		t.Fatalf("address must be subscribed on missing chain")
+	}
+}
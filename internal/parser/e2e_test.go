@@ -0,0 +1,163 @@
+package parser_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/jsonrpc"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// Fixtures are replayed by default, to record them from RPC run
+//
+//	go test ./internal/parser -run Test_EndToEnd -record -rpc http://localhost:8545
+//
+// Committed fixture is a synthetic block mined by cmd/simnode started with --genesis 1000,
+// golden file is rewritten on every recording. Mainnet capture is still pending, it needs network access to record.
+var (
+	record = flag.Bool("record", false, "record RPC fixtures instead of replaying them")
+	rpcURL = flag.String("rpc", "", "RPC endpoint to record fixtures from")
+)
+
+const (
+	// number of simnode block, low enough to not be mistaken for mainnet one
+	e2eBlock    = 1010
+	fixturePath = "testdata/simnode.jsonl"
+	goldenPath  = "testdata/simnode.golden.json"
+)
+
+var e2eAddresses = []types.Address{
+	types.MustParseAddress("0xe93685f3bba03016f02bd1828badd6195988d950"),
+	types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"),
+}
+
+// headTransport answers eth_blockNumber with fixed head, so exactly one block is processed in both modes
+type headTransport struct {
+	head int
+	next http.RoundTripper
+}
+
+func (t *headTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var r jsonrpc.Request
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, err
+	}
+
+	if r.Method != "eth_blockNumber" {
+		return t.next.RoundTrip(req)
+	}
+
+	resp := fmt.Sprintf(`{"jsonrpc":"2.0","id":%q,"result":"0x%x"}`, r.ID, t.head)
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(resp))),
+		Request:    req,
+	}, nil
+}
+
+func Test_EndToEnd(t *testing.T) {
+	url := "http://replay.invalid"
+
+	var transport http.RoundTripper
+	if *record {
+		if *rpcURL == "" {
+			t.Fatalf("-rpc is required to record fixtures")
+		}
+		url = *rpcURL
+
+		f, err := os.Create(fixturePath)
+		if err != nil {
+			t.Fatalf("failed to create fixture: %v", err)
+		}
+		defer f.Close()
+
+		transport = jsonrpc.NewRecordingTransport(http.DefaultTransport, f)
+	} else {
+		replay, err := jsonrpc.LoadReplayTransport(fixturePath)
+		if err != nil {
+			t.Fatalf("failed to load fixture: %v", err)
+		}
+		transport = replay
+	}
+
+	rpc := jsonrpc.NewClientWithTransport(&headTransport{head: e2eBlock + 1, next: transport})
+
+	conf := config.ChainConfig{
+		ChainID:        1,
+		BlocksInterval: 10 * time.Millisecond,
+		StartBlock:     e2eBlock,
+	}
+
+	p := parser.New(conf, ethclient.NewWithRPC(url, rpc), memory.New(), events.NewBroker())
+	for _, address := range e2eAddresses {
+		p.Subscribe(address)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		p.Start(ctx)
+	}()
+
+	waitBlock(t, p, e2eBlock)
+	cancel()
+	<-done
+
+	if failed := p.Status().FailedBlocks; failed != 0 {
+		t.Fatalf("failed blocks count is not zero, got %d", failed)
+	}
+
+	got := make(map[types.Address][]types.Transaction, len(e2eAddresses))
+	for _, address := range e2eAddresses {
+		txs := p.GetTransactions(context.Background(), address)
+		// receipts are fetched concurrently, so storing order differs between runs
+		slices.SortFunc(txs, func(a, b types.Transaction) int { return strings.Compare(a.Hash, b.Hash) })
+		got[address] = txs
+	}
+
+	gotJSON, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal transactions: %v", err)
+	}
+
+	if *record {
+		if err := os.WriteFile(goldenPath, append(gotJSON, '\n'), 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(want), gotJSON) {
+		t.Fatalf("transactions are not equal to golden file %s, got\n%s", goldenPath, gotJSON)
+	}
+}
@@ -0,0 +1,7 @@
+package parser
+
+// exported for tests of parser_test package
+var (
+	NewRateCounter = newRateCounter
+	ETA            = eta
+)
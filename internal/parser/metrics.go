@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"strconv"
+
+	"github.com/avelex/blockchain-parser/internal/metrics"
+)
+
+var (
+	headBlockGauge    = metrics.NewGaugeVec("parser_head_block", "Latest block number reported by RPC.", "chain_id")
+	currentBlockGauge = metrics.NewGaugeVec("parser_current_block", "Last processed block number.", "chain_id")
+	lagGauge          = metrics.NewGaugeVec("parser_lag_blocks", "Number of blocks between head and last processed block.", "chain_id")
+	subscribersGauge  = metrics.NewGaugeVec("parser_subscribers", "Number of subscribed addresses.", "chain_id")
+
+	blocksProcessed = metrics.NewCounterVec("parser_blocks_processed_total", "Number of processed blocks.", "chain_id")
+
+	blockReceipts = metrics.NewHistogramVec("parser_block_receipts", "Number of transaction receipts fetched per block.",
+		[]float64{0, 10, 50, 100, 200, 300, 500, 1000}, "chain_id")
+	saveDuration = metrics.NewHistogram("repository_save_duration_seconds", "Duration of committing block transactions to repository.",
+		metrics.DefaultBuckets)
+)
+
+// chainMetrics are metrics of a single chain parser
+type chainMetrics struct {
+	headBlock       *metrics.Gauge
+	currentBlock    *metrics.Gauge
+	lag             *metrics.Gauge
+	subscribers     *metrics.Gauge
+	blocksProcessed *metrics.Counter
+	blockReceipts   *metrics.Histogram
+}
+
+func newChainMetrics(chainID uint64) chainMetrics {
+	label := strconv.FormatUint(chainID, 10)
+
+	return chainMetrics{
+		headBlock:       headBlockGauge.WithLabelValues(label),
+		currentBlock:    currentBlockGauge.WithLabelValues(label),
+		lag:             lagGauge.WithLabelValues(label),
+		subscribers:     subscribersGauge.WithLabelValues(label),
+		blocksProcessed: blocksProcessed.WithLabelValues(label),
+		blockReceipts:   blockReceipts.WithLabelValues(label),
+	}
+}
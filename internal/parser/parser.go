@@ -2,56 +2,156 @@ package parser
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
-	"strings"
+	"maps"
+	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/avelex/blockchain-parser/config"
 	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/events"
 	"github.com/avelex/blockchain-parser/internal/repository"
 	"github.com/avelex/blockchain-parser/internal/types"
 )
 
+// attempts of live block before it's left as a gap
+const blockAttempts = 5
+
 type Parser interface {
+	// id of the parsed chain
+	ChainID() uint64
+	// chain name from config or chain id
+	Name() string
 	// last parsed block
 	GetCurrentBlock() int
 	// add address to observer
-	Subscribe(address string) bool
+	Subscribe(address types.Address) bool
 	// list of inbound or outbound transactions for an address
-	GetTransactions(ctx context.Context, address string) []types.Transaction
+	GetTransactions(ctx context.Context, address types.Address) []types.Transaction
+	// number of blocks between chain head and last parsed block
+	Lag() int
+	// time of the last successful RPC call
+	LastRPCSuccess() time.Time
+	// check repository is reachable
+	PingRepository(ctx context.Context) error
+	// sync progress
+	Status() Status
 }
 
 type BlockchainParser struct {
 	subMu       *sync.RWMutex
-	subscribers map[string]struct{}
+	subscribers map[types.Address]struct{}
 
 	currentBlock atomic.Int64
+	headBlock    atomic.Int64
+	failedBlocks atomic.Int64
+	rate         *rateCounter
+
+	// blocks interval may be changed by config reload, listenBlocks is notified to reset ticker
+	blocksInterval  atomic.Int64
+	intervalChanged chan struct{}
+
+	// last processed block loaded from repository on start
+	checkpoint int
 
-	conf   config.Config
+	// recent hashes, saved transactions and unconfirmed events by block number, used only by processBlocks
+	recentHashes       map[int]string
+	recentTransactions map[int]map[types.Address][]types.Transaction
+	pendingEvents      map[int][]events.Event
+
+	metrics chainMetrics
+
+	conf   config.ChainConfig
 	client *ethclient.Client
 	repo   repository.Repository
+	broker *events.Broker
 }
 
-func New(conf config.Config, client *ethclient.Client, repo repository.Repository) *BlockchainParser {
-	return &BlockchainParser{
-		subMu:        &sync.RWMutex{},
-		subscribers:  make(map[string]struct{}),
-		currentBlock: atomic.Int64{},
-		conf:         conf,
-		client:       client,
-		repo:         repo,
+// New creates parser of the chain, conf.ChainID must be already validated against RPC
+func New(conf config.ChainConfig, client *ethclient.Client, repo repository.Repository, broker *events.Broker) *BlockchainParser {
+	p := &BlockchainParser{
+		subMu:           &sync.RWMutex{},
+		subscribers:     make(map[types.Address]struct{}),
+		currentBlock:    atomic.Int64{},
+		rate:            newRateCounter(rateWindow),
+		intervalChanged: make(chan struct{}, 1),
+		recentHashes:    make(map[int]string),
+		pendingEvents:   make(map[int][]events.Event),
+
+		recentTransactions: make(map[int]map[types.Address][]types.Transaction),
+		metrics:            newChainMetrics(conf.ChainID),
+		conf:               conf,
+		client:             client,
+		repo:               repo,
+		broker:             broker,
 	}
+	p.blocksInterval.Store(int64(conf.BlocksInterval))
+
+	return p
+}
+
+// SetBlocksInterval changes how often chain head is polled by the running parser
+func (p *BlockchainParser) SetBlocksInterval(d time.Duration) {
+	p.blocksInterval.Store(int64(d))
+
+	select {
+	case p.intervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (p *BlockchainParser) ChainID() uint64 {
+	return p.conf.ChainID
+}
+
+// Name returns chain name from config or chain ID
+func (p *BlockchainParser) Name() string {
+	if p.conf.Name != "" {
+		return p.conf.Name
+	}
+	return strconv.FormatUint(p.conf.ChainID, 10)
 }
 
 func (p *BlockchainParser) GetCurrentBlock() int {
 	return int(p.currentBlock.Load())
 }
 
-func (p *BlockchainParser) Subscribe(address string) bool {
-	address = strings.ToLower(address)
+// Lag returns number of blocks between head and last processed block,
+// zero until both head is fetched and first block is processed
+func (p *BlockchainParser) Lag() int {
+	head, current := p.headBlock.Load(), p.currentBlock.Load()
+	if head == 0 || current == 0 {
+		return 0
+	}
+	return int(max(head-current, 0))
+}
+
+func (p *BlockchainParser) LastRPCSuccess() time.Time {
+	return p.client.LastSuccess()
+}
 
+func (p *BlockchainParser) PingRepository(ctx context.Context) error {
+	return p.repo.Ping(ctx)
+}
+
+// Subscribe adds address to observer and persists it in repository, so it's restored on start
+func (p *BlockchainParser) Subscribe(address types.Address) bool {
+	if !p.addSubscriber(address) {
+		return false
+	}
+
+	if err := p.repo.SaveSubscription(context.Background(), p.conf.ChainID, address); err != nil {
+		slog.Error("failed to save subscription", "chain", p.Name(), "address", address, "error", err)
+	}
+
+	return true
+}
+
+func (p *BlockchainParser) addSubscriber(address types.Address) bool {
 	p.subMu.Lock()
 	defer p.subMu.Unlock()
 
@@ -60,14 +160,13 @@ func (p *BlockchainParser) Subscribe(address string) bool {
 	}
 
 	p.subscribers[address] = struct{}{}
+	p.metrics.subscribers.Set(float64(len(p.subscribers)))
 
 	return true
 }
 
-func (p *BlockchainParser) GetTransactions(ctx context.Context, address string) []types.Transaction {
-	address = strings.ToLower(address)
-
-	tx, err := p.repo.GetTransactions(ctx, address)
+func (p *BlockchainParser) GetTransactions(ctx context.Context, address types.Address) []types.Transaction {
+	tx, err := p.repo.GetTransactions(ctx, p.conf.ChainID, address)
 	if err != nil {
 		return []types.Transaction{}
 	}
@@ -75,6 +174,10 @@ func (p *BlockchainParser) GetTransactions(ctx context.Context, address string)
 }
 
 func (p *BlockchainParser) Start(ctx context.Context) error {
+	if err := p.restore(ctx); err != nil {
+		return err
+	}
+
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 
@@ -101,37 +204,81 @@ func (p *BlockchainParser) Start(ctx context.Context) error {
 	return nil
 }
 
+// restore loads persisted subscriptions and the last processed block from repository
+func (p *BlockchainParser) restore(ctx context.Context) error {
+	addresses, err := p.repo.Subscriptions(ctx, p.conf.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+
+	for _, address := range addresses {
+		p.addSubscriber(address)
+	}
+
+	ranges, err := p.repo.ProcessedRanges(ctx, p.conf.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to load processed blocks: %w", err)
+	}
+
+	if len(ranges) > 0 {
+		p.checkpoint = ranges[len(ranges)-1].To
+	}
+
+	slog.Info("Restored state", "chain", p.Name(), "subscriptions", len(addresses), "checkpoint", p.checkpoint)
+
+	return nil
+}
+
 func (p *BlockchainParser) listenBlocks(ctx context.Context, pub chan<- int) {
-	ticker := time.NewTicker(p.conf.BlocksInterval)
+	ticker := time.NewTicker(time.Duration(p.blocksInterval.Load()))
 	defer ticker.Stop()
 
 	var startBlock int
 
 	if p.conf.StartBlock != 0 {
-		slog.Info("Start from block", "number", p.conf.StartBlock)
+		slog.Info("Start from block", "chain", p.Name(), "number", p.conf.StartBlock)
 		startBlock = p.conf.StartBlock
 	}
 
+	// blocks before checkpoint are already in repository, e.g. after restart with file storage or restored snapshot
+	if checkpoint := p.checkpoint; checkpoint != 0 && checkpoint >= startBlock {
+		slog.Info("Resume after checkpoint", "chain", p.Name(), "checkpoint", checkpoint)
+		startBlock = checkpoint + 1
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-p.intervalChanged:
+			interval := time.Duration(p.blocksInterval.Load())
+			ticker.Reset(interval)
+			slog.Info("Blocks interval changed", "chain", p.Name(), "interval", interval)
 		case <-ticker.C:
 			currentBlock, err := p.client.BlockNumber(ctx)
 			if err != nil {
-				slog.Warn("failed to get current block number", "error", err)
+				slog.Warn("failed to get current block number", "chain", p.Name(), "error", err)
 				continue
 			}
 
+			p.headBlock.Store(int64(currentBlock))
+			p.metrics.headBlock.Set(float64(currentBlock))
+			p.updateLag()
+
 			if startBlock == 0 {
 				startBlock = currentBlock
 			} else if startBlock == currentBlock {
-				slog.Info("No new blocks, wait for next block...", "startBlock", startBlock, "currentBlock", currentBlock)
+				slog.Info("No new blocks, wait for next block...", "chain", p.Name(), "startBlock", startBlock, "currentBlock", currentBlock)
 				continue
 			}
 
 			for i := startBlock; i < currentBlock; i++ {
-				pub <- i
+				// processing may be already stopped
+				select {
+				case pub <- i:
+				case <-ctx.Done():
+					return
+				}
 			}
 
 			startBlock = currentBlock
@@ -139,77 +286,221 @@ func (p *BlockchainParser) listenBlocks(ctx context.Context, pub chan<- int) {
 	}
 }
 
-// NOTE: add processing for failed blocks and transactions
+// processBlocks processes published blocks, failed block is retried every blocks interval
+// until it's processed or attempts are exhausted, then it's left as a gap to fill with backfill
 func (p *BlockchainParser) processBlocks(ctx context.Context, sub <-chan int) {
-	for blockNumber := range sub {
-		if isContextDone(ctx) {
-			slog.Info("Context done, stop processing blocks")
+	// failed attempts by block number
+	failed := make(map[int]int)
+
+	retry := time.NewTicker(time.Duration(p.blocksInterval.Load()))
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Context done, stop processing blocks", "chain", p.Name())
 			return
+		case number := <-sub:
+			p.handleBlock(ctx, number, failed)
+		case <-retry.C:
+			for _, number := range slices.Sorted(maps.Keys(failed)) {
+				p.handleBlock(ctx, number, failed)
+			}
 		}
+	}
+}
 
-		start := time.Now()
-		slog.Info("Processing block", "number", blockNumber)
+// handleBlock processes and publishes block, failed block is kept in failed until attempts are exhausted
+func (p *BlockchainParser) handleBlock(ctx context.Context, number int, failed map[int]int) {
+	start := time.Now()
+	slog.Info("Processing block", "chain", p.Name(), "number", number)
 
-		bh, err := p.client.BlockHeaderByNumber(ctx, blockNumber)
-		if err != nil {
-			slog.Error("failed to get block header", "number", blockNumber, "error", err)
-			continue
+	block, err := p.ProcessBlock(ctx, number)
+	if err != nil {
+		// interrupted block is processed after restart
+		if ctx.Err() != nil {
+			return
 		}
 
-		txChan := make(chan string, 5)
-		result := make(chan *ethclient.TransactionReceipt, 1)
+		p.failedBlocks.Add(1)
+		failed[number]++
 
-		for i := 0; i < cap(txChan); i++ {
-			go p.processTransactions(ctx, txChan, result)
+		if failed[number] < blockAttempts {
+			slog.Error("failed to process block, retry later", "chain", p.Name(), "number", number, "attempt", failed[number], "error", err)
+			return
 		}
 
-		go func() {
-			for _, tx := range bh.Transactions {
-				txChan <- tx
-			}
-		}()
+		delete(failed, number)
+		slog.Error("failed to process block, fill the gap with backfill", "chain", p.Name(), "number", number, "attempts", blockAttempts, "error", err)
+		return
+	}
 
-		subTx := make(map[string][]types.Transaction, len(bh.Transactions))
+	delete(failed, number)
 
-		for i := 0; i < len(bh.Transactions); i++ {
-			receipt := <-result
+	// blocks replaced by reorg are published before the block on top of them
+	p.handleReorg(ctx, block.Header)
+	p.publishBlock(block)
 
-			if receipt.IsFailed() {
-				continue
-			}
+	slog.Info("Processed block", "chain", p.Name(), "number", number, "tx_count", len(block.Header.Transactions), "dur", time.Since(start))
+}
 
-			tx := types.NewTransaction(receipt.Hash, receipt.From, receipt.To, bh.Timestamp)
+// publishBlock publishes transaction events of processed block and advances current block
+func (p *BlockchainParser) publishBlock(block Block) {
+	number := int(block.Header.Number)
 
-			if p.subscriberExists(receipt.From) {
-				subTx[receipt.From] = append(subTx[receipt.From], tx)
-			}
+	var blockEvents []events.Event
 
-			if p.subscriberExists(receipt.To) {
-				subTx[receipt.To] = append(subTx[receipt.To], tx)
+	for address, txs := range block.Transactions {
+		for _, tx := range txs {
+			e := events.Event{
+				Type:        events.TypeTransaction,
+				ChainID:     p.conf.ChainID,
+				Address:     address,
+				Block:       number,
+				Transaction: &tx,
 			}
+			p.broker.Publish(e)
+			blockEvents = append(blockEvents, e)
 		}
+	}
 
-		// safe close, all transactions processed
-		close(txChan)
-		close(result)
+	p.recentTransactions[number] = block.Transactions
+	p.confirmBlocks(block.Header, blockEvents)
 
-		for address, txs := range subTx {
-			if err := p.repo.SaveTransactions(ctx, address, txs); err != nil {
-				slog.Error("failed to save transactions", "address", address, "error", err)
-			}
+	// reprocessed block is behind current one
+	if int64(number) > p.currentBlock.Load() {
+		p.currentBlock.Store(int64(number))
+		p.metrics.currentBlock.Set(float64(number))
+	}
+
+	p.rate.Add(time.Now())
+	p.metrics.blocksProcessed.Inc()
+	p.metrics.blockReceipts.Observe(float64(len(block.Header.Transactions)))
+	p.updateLag()
+}
+
+// Block is a processed block with transactions of subscribed addresses
+type Block struct {
+	Header       *ethclient.BlockHeader
+	Transactions map[types.Address][]types.Transaction
+	// receipts failed to fetch, their transactions are missing
+	MissingReceipts int
+}
+
+// ProcessBlock fetches block receipts, saves transactions of subscribed addresses and marks block processed.
+// It's shared by live loop and backfill, events are published by the caller.
+// Transactions and processed block are committed together, so failed block leaves nothing saved.
+// Block with missing receipts is not committed, so it's never marked processed with transactions lost.
+func (p *BlockchainParser) ProcessBlock(ctx context.Context, number int) (Block, error) {
+	return p.processBlock(ctx, number, p.recentTransactions[number])
+}
+
+// processBlock is ProcessBlock deleting stale transactions in the same commit, e.g. of block replaced by reorg
+func (p *BlockchainParser) processBlock(ctx context.Context, number int, stale map[types.Address][]types.Transaction) (Block, error) {
+	block, err := p.fetchBlock(ctx, number)
+	if err != nil {
+		return Block{}, err
+	}
+
+	if err := checkComplete(ctx, block); err != nil {
+		return Block{}, err
+	}
+
+	batch := repository.NewBatch(p.conf.ChainID)
+	for address, txs := range stale {
+		batch.DeleteTransactions(address, txs)
+	}
+	for address, txs := range block.Transactions {
+		batch.SaveTransactions(address, txs)
+	}
+	batch.MarkProcessed(number)
+
+	saveStart := time.Now()
+	err = p.repo.Commit(ctx, batch)
+	saveDuration.Observe(time.Since(saveStart).Seconds())
+
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to commit block: %w", err)
+	}
+
+	return block, nil
+}
+
+// fetchBlock fetches block receipts and filters transactions of subscribed addresses without saving them
+func (p *BlockchainParser) fetchBlock(ctx context.Context, number int) (Block, error) {
+	bh, err := p.client.BlockHeaderByNumber(ctx, number)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to get block header: %w", err)
+	}
+
+	txChan := make(chan string, 5)
+	result := make(chan *ethclient.TransactionReceipt, 1)
+
+	for i := 0; i < cap(txChan); i++ {
+		go p.processTransactions(ctx, txChan, result)
+	}
+
+	go func() {
+		for _, tx := range bh.Transactions {
+			txChan <- string(tx)
 		}
+	}()
 
-		p.currentBlock.Store(int64(blockNumber))
+	block := Block{
+		Header:       bh,
+		Transactions: make(map[types.Address][]types.Transaction),
+	}
 
-		slog.Info("Processed block", "number", blockNumber, "tx_count", len(bh.Transactions), "dur", time.Since(start))
+	for i := 0; i < len(bh.Transactions); i++ {
+		receipt := <-result
+
+		if receipt == nil {
+			block.MissingReceipts++
+			continue
+		}
+
+		if receipt.IsFailed() {
+			continue
+		}
+
+		tx := types.NewTransaction(p.conf.ChainID, string(receipt.Hash), receipt.From, receipt.To, int64(bh.Timestamp))
+
+		if p.subscriberExists(receipt.From) {
+			block.Transactions[receipt.From] = append(block.Transactions[receipt.From], tx)
+		}
+
+		// self-transfer is listed once
+		if receipt.To != receipt.From && p.subscriberExists(receipt.To) {
+			block.Transactions[receipt.To] = append(block.Transactions[receipt.To], tx)
+		}
+	}
+
+	// safe close, all transactions processed
+	close(txChan)
+	close(result)
+
+	return block, nil
+}
+
+// checkComplete returns error if block is fetched partially, e.g. receipts failed or ctx is done in the middle
+func checkComplete(ctx context.Context, block Block) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("block %d is interrupted: %w", block.Header.Number, err)
+	}
+
+	if block.MissingReceipts > 0 {
+		return fmt.Errorf("%w: %d of %d", ErrMissingReceipts, block.MissingReceipts, len(block.Header.Transactions))
 	}
+
+	return nil
 }
 
 func (p *BlockchainParser) processTransactions(ctx context.Context, txChan <-chan string, result chan<- *ethclient.TransactionReceipt) {
 	for txHash := range txChan {
 		receipt, err := p.client.TransactionReceipt(ctx, txHash)
 		if err != nil {
-			result <- ethclient.EmptyFailedReceipt()
+			// counted as missing receipt
+			result <- nil
 			continue
 		}
 
@@ -217,20 +508,92 @@ func (p *BlockchainParser) processTransactions(ctx context.Context, txChan <-cha
 	}
 }
 
-func (p *BlockchainParser) subscriberExists(address string) bool {
-	p.subMu.RLock()
-	defer p.subMu.RUnlock()
+func (p *BlockchainParser) updateLag() {
+	p.metrics.lag.Set(float64(p.Lag()))
+}
 
-	_, ok := p.subscribers[address]
+// handleReorg walks back from the block while parent hash differs from the processed one.
+// Every replaced block is reprocessed, its stale transactions are deleted in the same commit,
+// then reprocessed blocks are published in ascending order.
+func (p *BlockchainParser) handleReorg(ctx context.Context, bh *ethclient.BlockHeader) {
+	var replaced []Block
 
-	return ok
+	parentHash := string(bh.ParentHash)
+
+	for number := int(bh.Number) - 1; ; number-- {
+		prevHash, ok := p.recentHashes[number]
+		if !ok || parentHash == "" || prevHash == parentHash {
+			break
+		}
+
+		slog.Warn("Chain reorganization detected", "chain", p.Name(), "number", number, "old_hash", prevHash, "new_hash", parentHash)
+
+		// transactions of replaced block will never be confirmed
+		delete(p.pendingEvents, number)
+
+		p.broker.Publish(events.Event{
+			Type:    events.TypeReorg,
+			ChainID: p.conf.ChainID,
+			Block:   number,
+		})
+
+		block, err := p.processBlock(ctx, number, p.recentTransactions[number])
+		if err != nil {
+			slog.Error("failed to reprocess replaced block", "chain", p.Name(), "number", number, "error", err)
+			p.failedBlocks.Add(1)
+			break
+		}
+
+		replaced = append(replaced, block)
+		parentHash = string(block.Header.ParentHash)
+	}
+
+	for i := len(replaced) - 1; i >= 0; i-- {
+		p.publishBlock(replaced[i])
+	}
 }
 
-func isContextDone(ctx context.Context) bool {
-	select {
-	case <-ctx.Done():
-		return true
-	default:
+// confirmBlocks remembers block events and publishes confirmations for blocks deep enough in the chain
+func (p *BlockchainParser) confirmBlocks(bh *ethclient.BlockHeader, blockEvents []events.Event) {
+	head := int(bh.Number)
+
+	p.recentHashes[head] = string(bh.Hash)
+	if len(blockEvents) > 0 {
+		p.pendingEvents[head] = blockEvents
+	}
+
+	confirmed := head - p.conf.Confirmations
+
+	for number, pending := range p.pendingEvents {
+		if number > confirmed {
+			continue
+		}
+
+		for _, e := range pending {
+			e.Type = events.TypeConfirmation
+			p.broker.Publish(e)
+		}
+
+		delete(p.pendingEvents, number)
+	}
+
+	for number := range p.recentHashes {
+		if number < confirmed {
+			delete(p.recentHashes, number)
+			delete(p.recentTransactions, number)
+		}
+	}
+}
+
+func (p *BlockchainParser) subscriberExists(address types.Address) bool {
+	if address.IsZero() {
 		return false
 	}
+
+	p.subMu.RLock()
+	defer p.subMu.RUnlock()
+
+	_, ok := p.subscribers[address]
+
+	return ok
 }
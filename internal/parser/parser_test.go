@@ -0,0 +1,428 @@
+package parser_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/ethclient"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/parser"
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/file"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/simnode"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+const (
+	testChainID = 1
+	testGenesis = 100
+	waitTimeout = 5 * time.Second
+)
+
+var (
+	alice = types.MustParseAddress("0xe93685f3bba03016f02bd1828badd6195988d950")
+	bob   = types.MustParseAddress("0x22a7a914cf352f7361c199188a23da94fe71b277")
+	carol = types.MustParseAddress("0xfe556e4f848c82093d0a33cc41761d18f67099ca")
+)
+
+// startParser runs parser against node from the block after genesis until the test ends
+func startParser(t *testing.T, node *simnode.Node, confirmations int) (*parser.BlockchainParser, <-chan events.Event) {
+	t.Helper()
+
+	server := httptest.NewServer(node)
+
+	conf := config.ChainConfig{
+		ChainID:        testChainID,
+		BlocksInterval: 10 * time.Millisecond,
+		StartBlock:     testGenesis + 1,
+		Confirmations:  confirmations,
+	}
+
+	broker := events.NewBroker()
+	eventsChan, cancelEvents := broker.Subscribe(1024)
+
+	p := parser.New(conf, ethclient.New(server.URL), memory.New(), broker)
+	p.Subscribe(alice)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		p.Start(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		cancelEvents()
+		server.Close()
+	})
+
+	return p, eventsChan
+}
+
+// waitBlock waits until parser processes block
+func waitBlock(t *testing.T, p *parser.BlockchainParser, number int) {
+	t.Helper()
+
+	deadline := time.Now().Add(waitTimeout)
+	for p.GetCurrentBlock() < number {
+		if time.Now().After(deadline) {
+			t.Fatalf("block %d is not processed, current block %d", number, p.GetCurrentBlock())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitEvent waits for event of type, skipping other events
+func waitEvent(t *testing.T, eventsChan <-chan events.Event, eventType events.Type) events.Event {
+	t.Helper()
+
+	timeout := time.After(waitTimeout)
+	for {
+		select {
+		case e := <-eventsChan:
+			if e.Type == eventType {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", eventType)
+		}
+	}
+}
+
+func Test_ParseTransactions(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		tx     simnode.Transaction
+		wantTx bool
+	}{
+		{
+			desc:   "Outbound",
+			tx:     simnode.Transaction{From: alice, To: bob},
+			wantTx: true,
+		},
+		{
+			desc:   "Inbound",
+			tx:     simnode.Transaction{From: bob, To: alice},
+			wantTx: true,
+		},
+		{
+			desc:   "Contract Creation",
+			tx:     simnode.Transaction{From: alice},
+			wantTx: true,
+		},
+		{
+			desc: "Reverted",
+			tx:   simnode.Transaction{From: alice, To: bob, Failed: true},
+		},
+		{
+			desc: "Other Addresses",
+			tx:   simnode.Transaction{From: bob, To: carol},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			node := simnode.New(testChainID, testGenesis)
+			block := node.Mine(tC.tx)
+			// parser processes blocks behind the head
+			node.Mine()
+
+			p, _ := startParser(t, node, 0)
+			waitBlock(t, p, block.Number)
+
+			transactions := p.GetTransactions(context.Background(), alice)
+
+			if !tC.wantTx {
+				if len(transactions) != 0 {
+					t.Fatalf("transactions are not expected, got %v", transactions)
+				}
+				return
+			}
+
+			if len(transactions) != 1 {
+				t.Fatalf("transactions count is not equal to 1, got %d", len(transactions))
+			}
+
+			want := types.NewTransaction(testChainID, block.Transactions[0].Hash, tC.tx.From, tC.tx.To, block.Timestamp)
+			if transactions[0] != want {
+				t.Fatalf("transaction is not equal, want %+v, got %+v", want, transactions[0])
+			}
+		})
+	}
+}
+
+func Test_ParseBlocksAfterRPCFailures(t *testing.T) {
+	node := simnode.New(testChainID, testGenesis)
+	node.FailNext("eth_blockNumber", 3)
+	node.SetRateLimit(20)
+
+	block := node.Mine(simnode.Transaction{From: bob, To: alice})
+	node.MineEmpty(3)
+
+	p, _ := startParser(t, node, 0)
+	waitBlock(t, p, block.Number)
+
+	if got := len(p.GetTransactions(context.Background(), alice)); got != 1 {
+		t.Fatalf("transactions count is not equal to 1, got %d", got)
+	}
+}
+
+func Test_Confirmations(t *testing.T) {
+	node := simnode.New(testChainID, testGenesis)
+	block := node.Mine(simnode.Transaction{From: alice, To: bob})
+	node.Mine()
+
+	p, eventsChan := startParser(t, node, 2)
+
+	e := waitEvent(t, eventsChan, events.TypeTransaction)
+	if e.Block != block.Number || e.Address != alice || e.ChainID != testChainID {
+		t.Fatalf("unexpected transaction event %+v", e)
+	}
+
+	// block is confirmed when 2 blocks on top of it are processed
+	node.MineEmpty(2)
+	waitBlock(t, p, block.Number+2)
+
+	e = waitEvent(t, eventsChan, events.TypeConfirmation)
+	if e.Block != block.Number || e.Transaction.Hash != block.Transactions[0].Hash {
+		t.Fatalf("unexpected confirmation event %+v", e)
+	}
+}
+
+func Test_Lag(t *testing.T) {
+	node := simnode.New(testChainID, testGenesis)
+	node.MineEmpty(5)
+
+	idle := parser.New(config.ChainConfig{ChainID: testChainID}, ethclient.New("http://127.0.0.1:0"), memory.New(), events.NewBroker())
+	if got := idle.Lag(); got != 0 {
+		t.Fatalf("lag of not started parser is not equal, want 0, got %d", got)
+	}
+
+	p, _ := startParser(t, node, 0)
+	// parser processes blocks behind the head
+	waitBlock(t, p, node.Head().Number-1)
+
+	if got := p.Lag(); got != 1 {
+		t.Fatalf("lag is not equal, want 1, got %d", got)
+	}
+
+	if got := p.Status().BlocksBehind; got != p.Lag() {
+		t.Fatalf("blocks behind are not equal to lag, want %d, got %d", p.Lag(), got)
+	}
+}
+
+func Test_Reorg(t *testing.T) {
+	node := simnode.New(testChainID, testGenesis)
+	node.MineEmpty(2)
+	stale := node.Mine(simnode.Transaction{From: alice, To: bob})
+	node.Mine()
+
+	p, eventsChan := startParser(t, node, 0)
+	waitBlock(t, p, stale.Number)
+
+	// replace processed block and the head
+	node.Reorg(2)
+	canonical := node.Mine(simnode.Transaction{From: bob, To: alice})
+	node.MineEmpty(2)
+
+	e := waitEvent(t, eventsChan, events.TypeReorg)
+	if e.Block != stale.Number || e.ChainID != testChainID {
+		t.Fatalf("unexpected reorg event %+v, want block %d", e, stale.Number)
+	}
+
+	// replaced block is reprocessed
+	e = waitEvent(t, eventsChan, events.TypeTransaction)
+	if e.Block != canonical.Number || e.Transaction.Hash != canonical.Transactions[0].Hash {
+		t.Fatalf("unexpected transaction event %+v, want transaction %s", e, canonical.Transactions[0].Hash)
+	}
+
+	transactions := p.GetTransactions(context.Background(), alice)
+
+	want := []types.Transaction{types.NewTransaction(testChainID, canonical.Transactions[0].Hash, bob, alice, canonical.Timestamp)}
+	if !slices.Equal(want, transactions) {
+		t.Fatalf("transactions are not equal, want %+v, got %+v", want, transactions)
+	}
+}
+
+func Test_Scan(t *testing.T) {
+	node := simnode.New(testChainID, testGenesis)
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		block := node.Mine(simnode.Transaction{From: alice, To: bob}, simnode.Transaction{From: bob, To: carol})
+		want = append(want, block.Transactions[0].Hash)
+	}
+	// missing receipt makes block retried
+	node.FailNext("eth_getTransactionReceipt", 1)
+
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	repo := memory.New()
+	p := parser.New(config.ChainConfig{ChainID: testChainID}, ethclient.New(server.URL), repo, events.NewBroker())
+	p.Subscribe(alice)
+
+	var got []string
+	next := testGenesis + 1
+
+	err := p.Scan(context.Background(), testGenesis+1, testGenesis+20, 4, func(number int, block parser.Block, err error) error {
+		if err != nil {
+			t.Fatalf("failed to scan block %d: %v", number, err)
+		}
+		if number != next {
+			t.Fatalf("block is out of order, want %d, got %d", next, number)
+		}
+		next++
+
+		for _, tx := range block.Transactions[alice] {
+			got = append(got, tx.Hash)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to scan: %v", err)
+	}
+
+	if !slices.Equal(want, got) {
+		t.Fatalf("transactions are not equal, want %v, got %v", want, got)
+	}
+
+	if txs, _ := repo.GetTransactions(context.Background(), testChainID, alice); len(txs) != 0 {
+		t.Fatalf("scan must not save transactions, got %d", len(txs))
+	}
+}
+
+func Test_ReprocessBlocks(t *testing.T) {
+	node := simnode.New(testChainID, testGenesis)
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		block := node.Mine(simnode.Transaction{From: alice, To: bob}, simnode.Transaction{From: alice, To: alice})
+		want = append(want, block.Transactions[0].Hash, block.Transactions[1].Hash)
+	}
+
+	// receipts are fetched concurrently, so order within block is not fixed
+	slices.Sort(want)
+
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	testCases := []struct {
+		desc string
+		open func(t *testing.T) repository.Repository
+	}{
+		{
+			desc: "Memory",
+			open: func(t *testing.T) repository.Repository {
+				return memory.New()
+			},
+		},
+		{
+			desc: "File",
+			open: func(t *testing.T) repository.Repository {
+				repo, err := file.Open(filepath.Join(t.TempDir(), "transactions.jsonl"))
+				if err != nil {
+					t.Fatalf("failed to open repository: %v", err)
+				}
+				t.Cleanup(func() { repo.Close() })
+				return repo
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			repo := tC.open(t)
+
+			p := parser.New(config.ChainConfig{ChainID: testChainID}, ethclient.New(server.URL), repo, events.NewBroker())
+			p.Subscribe(alice)
+
+			// e.g. restart from start_block or backfill overlapping live parsing
+			for i := 0; i < 2; i++ {
+				for number := testGenesis + 1; number <= testGenesis+5; number++ {
+					if _, err := p.ProcessBlock(ctx, number); err != nil {
+						t.Fatalf("failed to process block %d: %v", number, err)
+					}
+				}
+			}
+
+			txs, err := repo.GetTransactions(ctx, testChainID, alice)
+			if err != nil {
+				t.Fatalf("failed to get transactions: %v", err)
+			}
+
+			got := make([]string, 0, len(txs))
+			for _, tx := range txs {
+				got = append(got, tx.Hash)
+			}
+
+			slices.Sort(got)
+
+			if !slices.Equal(want, got) {
+				t.Fatalf("transactions are not equal, want %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func Test_ProcessBlockMissingReceipts(t *testing.T) {
+	node := simnode.New(testChainID, testGenesis)
+	node.Mine(simnode.Transaction{From: alice, To: bob}, simnode.Transaction{From: bob, To: alice})
+	node.FailNext("eth_getTransactionReceipt", 1)
+
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	ctx := context.Background()
+	repo := memory.New()
+
+	p := parser.New(config.ChainConfig{ChainID: testChainID}, ethclient.New(server.URL), repo, events.NewBroker())
+	p.Subscribe(alice)
+
+	if _, err := p.ProcessBlock(ctx, testGenesis+1); !errors.Is(err, parser.ErrMissingReceipts) {
+		t.Fatalf("error is not equal, want %v, got %v", parser.ErrMissingReceipts, err)
+	}
+
+	// nothing of partially fetched block is committed, so it's found as a gap
+	ranges, err := repo.ProcessedRanges(ctx, testChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if txs, _ := repo.GetTransactions(ctx, testChainID, alice); len(ranges) != 0 || len(txs) != 0 {
+		t.Fatalf("block is committed, got ranges %v and transactions %v", ranges, txs)
+	}
+
+	if _, err := p.ProcessBlock(ctx, testGenesis+1); err != nil {
+		t.Fatalf("failed to process block again: %v", err)
+	}
+}
+
+func Test_RetryFailedBlock(t *testing.T) {
+	node := simnode.New(testChainID, testGenesis)
+	block := node.Mine(simnode.Transaction{From: bob, To: alice})
+	node.MineEmpty(3)
+
+	// the first attempt misses receipt, block is committed on retry
+	node.FailNext("eth_getTransactionReceipt", 1)
+
+	p, eventsChan := startParser(t, node, 0)
+
+	e := waitEvent(t, eventsChan, events.TypeTransaction)
+	if e.Block != block.Number || e.Transaction.Hash != block.Transactions[0].Hash {
+		t.Fatalf("transaction event is not equal, want block %d, got %+v", block.Number, e)
+	}
+
+	if failed := p.Status().FailedBlocks; failed != 1 {
+		t.Fatalf("failed blocks count is not equal, want 1, got %d", failed)
+	}
+}
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	scanAttempts     = 3
+	scanRetryBackoff = time.Second
+)
+
+// ErrMissingReceipts is returned for processed or scanned block if some of its receipts failed to fetch
+var ErrMissingReceipts = errors.New("missing receipts")
+
+// ScanFunc is called by Scan for every block of range in ascending order,
+// err is set if block failed after all attempts, returned error stops the scan
+type ScanFunc func(number int, block Block, err error) error
+
+type scanResult struct {
+	number int
+	block  Block
+	err    error
+}
+
+// Scan processes blocks from..to inclusive with concurrency workers and passes them to fn in order,
+// transactions are not saved to repository and events are not published, so it doesn't affect live parsing.
+// Failed blocks are retried before they are passed to fn with error.
+func (p *BlockchainParser) Scan(ctx context.Context, from, to, concurrency int, fn ScanFunc) error {
+	if from > to {
+		return fmt.Errorf("invalid range %d-%d", from, to)
+	}
+
+	concurrency = max(concurrency, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// blocks in flight are limited, so one slow block doesn't buffer the whole range
+	window := make(chan struct{}, concurrency*4)
+	numbers := make(chan int)
+	results := make(chan scanResult, concurrency)
+
+	go func() {
+		defer close(numbers)
+
+		for number := from; number <= to; number++ {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case numbers <- number:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg := sync.WaitGroup{}
+	wg.Add(concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+
+			for number := range numbers {
+				block, err := p.scanBlock(ctx, number)
+
+				select {
+				case results <- scanResult{number: number, block: block, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]scanResult, cap(window))
+	next := from
+
+	for r := range results {
+		pending[r.number] = r
+
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			<-window
+
+			if err := fn(r.number, r.block, r.err); err != nil {
+				return err
+			}
+			next++
+		}
+	}
+
+	if next <= to {
+		return fmt.Errorf("scan stopped at block %d: %w", next, ctx.Err())
+	}
+
+	return nil
+}
+
+// scanBlock fetches block with retries, partially fetched block is retried as well
+func (p *BlockchainParser) scanBlock(ctx context.Context, number int) (Block, error) {
+	var err error
+
+	for attempt := 1; attempt <= scanAttempts; attempt++ {
+		var block Block
+
+		block, err = p.fetchBlock(ctx, number)
+		if err == nil {
+			err = checkComplete(ctx, block)
+		}
+
+		if err == nil {
+			return block, nil
+		}
+
+		if attempt == scanAttempts {
+			break
+		}
+
+		slog.Warn("failed to scan block, retrying", "chain", p.Name(), "number", number, "attempt", attempt, "error", err)
+
+		select {
+		case <-time.After(scanRetryBackoff * time.Duration(attempt)):
+		case <-ctx.Done():
+			return Block{}, ctx.Err()
+		}
+	}
+
+	return Block{}, err
+}
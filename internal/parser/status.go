@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"sync"
+	"time"
+)
+
+const rateWindow = time.Minute
+
+type Status struct {
+	ChainID      uint64 `json:"chain_id"`
+	Chain        string `json:"chain"`
+	HeadBlock    int    `json:"head_block"`
+	CurrentBlock int    `json:"current_block"`
+	BlocksBehind int    `json:"blocks_behind"`
+	// processing rate over the last minute
+	BlocksPerSecond float64 `json:"blocks_per_second"`
+	// estimated time to catch up with chain head, empty if unknown
+	ETA          string `json:"eta,omitempty"`
+	Subscribers  int    `json:"subscribers"`
+	FailedBlocks int    `json:"failed_blocks"`
+	RPCEndpoint  string `json:"rpc_endpoint"`
+}
+
+// rateCounter counts events in a sliding time window
+type rateCounter struct {
+	mu     *sync.Mutex
+	window time.Duration
+	times  []time.Time
+}
+
+func newRateCounter(window time.Duration) *rateCounter {
+	return &rateCounter{
+		mu:     &sync.Mutex{},
+		window: window,
+	}
+}
+
+func (r *rateCounter) Add(t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.times = append(r.times, t)
+	r.evict(t)
+}
+
+// Rate returns events per second in the window
+func (r *rateCounter) Rate(now time.Time) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.evict(now)
+
+	return float64(len(r.times)) / r.window.Seconds()
+}
+
+func (r *rateCounter) evict(now time.Time) {
+	i := 0
+	for i < len(r.times) && now.Sub(r.times[i]) > r.window {
+		i++
+	}
+	r.times = r.times[i:]
+}
+
+func (p *BlockchainParser) Status() Status {
+	p.subMu.RLock()
+	subscribers := len(p.subscribers)
+	p.subMu.RUnlock()
+
+	behind := p.Lag()
+	rate := p.rate.Rate(time.Now())
+
+	status := Status{
+		ChainID:         p.conf.ChainID,
+		Chain:           p.Name(),
+		HeadBlock:       int(p.headBlock.Load()),
+		CurrentBlock:    p.GetCurrentBlock(),
+		BlocksBehind:    behind,
+		BlocksPerSecond: rate,
+		Subscribers:     subscribers,
+		FailedBlocks:    int(p.failedBlocks.Load()),
+		RPCEndpoint:     p.client.Endpoint(),
+	}
+
+	status.ETA = eta(behind, rate)
+
+	return status
+}
+
+// eta estimates time to process blocks behind at rate, empty if rate is unknown
+func eta(behind int, rate float64) string {
+	switch {
+	case behind <= 0:
+		return "0s"
+	case rate > 0:
+		return time.Duration(float64(behind) / rate * float64(time.Second)).Round(time.Second).String()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,110 @@
+package parser_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/parser"
+)
+
+func Test_RateCounter(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+
+	testCases := []struct {
+		desc string
+		// offsets of added events from start
+		events []time.Duration
+		// offset of rate calculation from start
+		now  time.Duration
+		want float64
+	}{
+		{
+			desc: "No events",
+			now:  time.Second,
+			want: 0,
+		},
+		{
+			desc:   "Events in window",
+			events: []time.Duration{0, 2 * time.Second, 4 * time.Second},
+			now:    5 * time.Second,
+			want:   0.3,
+		},
+		{
+			desc:   "Event on window edge is counted",
+			events: []time.Duration{0, 10 * time.Second},
+			now:    10 * time.Second,
+			want:   0.2,
+		},
+		{
+			desc:   "Events older than window are evicted",
+			events: []time.Duration{0, 5 * time.Second, 12 * time.Second},
+			now:    16 * time.Second,
+			want:   0.1,
+		},
+		{
+			desc:   "All events are evicted",
+			events: []time.Duration{0, time.Second},
+			now:    time.Minute,
+			want:   0,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			counter := parser.NewRateCounter(10 * time.Second)
+			for _, offset := range tC.events {
+				counter.Add(start.Add(offset))
+			}
+
+			if got := counter.Rate(start.Add(tC.now)); got != tC.want {
+				t.Fatalf("rate is not equal, want %v, got %v", tC.want, got)
+			}
+		})
+	}
+}
+
+func Test_ETA(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		behind int
+		rate   float64
+		want   string
+	}{
+		{
+			desc:   "Caught up",
+			behind: 0,
+			rate:   2,
+			want:   "0s",
+		},
+		{
+			desc:   "Caught up with zero rate",
+			behind: 0,
+			rate:   0,
+			want:   "0s",
+		},
+		{
+			desc:   "Behind with zero rate is unknown",
+			behind: 10,
+			rate:   0,
+			want:   "",
+		},
+		{
+			desc:   "Behind",
+			behind: 150,
+			rate:   2.5,
+			want:   "1m0s",
+		},
+		{
+			desc:   "Rounded to seconds",
+			behind: 10,
+			rate:   3,
+			want:   "3s",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if got := parser.ETA(tC.behind, tC.rate); got != tC.want {
+				t.Fatalf("ETA is not equal, want %q, got %q", tC.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/ratelimit"
+)
+
+func Test_Allow(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		rate  float64
+		burst int
+		// requests of the same key in a row
+		requests int
+		allowed  int
+	}{
+		{
+			desc:     "Disabled",
+			rate:     0,
+			burst:    1,
+			requests: 100,
+			allowed:  100,
+		},
+		{
+			desc:     "Burst",
+			rate:     1,
+			burst:    5,
+			requests: 10,
+			allowed:  5,
+		},
+		{
+			desc:     "Zero burst allows single request",
+			rate:     1,
+			burst:    0,
+			requests: 3,
+			allowed:  1,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			limiter := ratelimit.New(tC.rate, tC.burst)
+
+			allowed := 0
+			for i := 0; i < tC.requests; i++ {
+				ok, wait := limiter.Allow("key")
+				if ok {
+					allowed++
+					continue
+				}
+
+				// tokens are refilled at rate per second
+				if wait <= 0 || wait > time.Duration(float64(time.Second)/tC.rate) {
+					t.Fatalf("wait is out of range, want (0, %v], got %v", time.Duration(float64(time.Second)/tC.rate), wait)
+				}
+			}
+
+			if allowed != tC.allowed {
+				t.Fatalf("allowed requests are not equal, want %d, got %d", tC.allowed, allowed)
+			}
+
+			// buckets are partitioned by key
+			if ok, _ := limiter.Allow("other"); !ok {
+				t.Fatalf("request of another key must be allowed")
+			}
+		})
+	}
+}
+
+func Test_Refill(t *testing.T) {
+	limiter := ratelimit.New(100, 1)
+
+	if ok, _ := limiter.Allow("key"); !ok {
+		t.Fatalf("first request must be allowed")
+	}
+
+	ok, wait := limiter.Allow("key")
+	if ok {
+		t.Fatalf("request over burst must be rejected")
+	}
+
+	time.Sleep(wait)
+
+	if ok, _ := limiter.Allow("key"); !ok {
+		t.Fatalf("request after wait must be allowed")
+	}
+}
+
+func Test_SetLimit(t *testing.T) {
+	limiter := ratelimit.New(1, 1)
+	limiter.Allow("key")
+
+	if ok, _ := limiter.Allow("key"); ok {
+		t.Fatalf("request over burst must be rejected")
+	}
+
+	limiter.SetLimit(0, 0)
+
+	if ok, _ := limiter.Allow("key"); !ok {
+		t.Fatalf("request must be allowed when limit is disabled")
+	}
+}
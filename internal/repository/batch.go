@@ -0,0 +1,40 @@
+package repository
+
+import "github.com/avelex/blockchain-parser/internal/types"
+
+// Batch is a unit of work of chain, its writes are committed together by Repository.Commit,
+// so transactions of block are never saved without block marked processed or vice versa
+type Batch struct {
+	ChainID      uint64                                `json:"chain_id"`
+	Transactions map[types.Address][]types.Transaction `json:"transactions,omitempty"`
+	// processed blocks, committed with transactions as checkpoint
+	Processed []BlockRange `json:"processed,omitempty"`
+	// hashes of transactions deleted before transactions are saved, e.g. of block replaced by reorg
+	Deleted map[types.Address][]string `json:"deleted,omitempty"`
+}
+
+func NewBatch(chainID uint64) *Batch {
+	return &Batch{
+		ChainID:      chainID,
+		Transactions: make(map[types.Address][]types.Transaction),
+		Deleted:      make(map[types.Address][]string),
+	}
+}
+
+func (b *Batch) SaveTransactions(address types.Address, transactions []types.Transaction) {
+	b.Transactions[address] = append(b.Transactions[address], transactions...)
+}
+
+func (b *Batch) MarkProcessed(number int) {
+	b.MarkProcessedRange(BlockRange{From: number, To: number})
+}
+
+func (b *Batch) MarkProcessedRange(r BlockRange) {
+	b.Processed = append(b.Processed, r)
+}
+
+func (b *Batch) DeleteTransactions(address types.Address, transactions []types.Transaction) {
+	for _, tx := range transactions {
+		b.Deleted[address] = append(b.Deleted[address], tx.Hash)
+	}
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// Format of exported transactions
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+var formats = []Format{FormatCSV, FormatJSONL, FormatParquet}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range formats {
+		if string(f) == strings.ToLower(s) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q, supported %v", ErrUnknownFormat, s, formats)
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatJSONL:
+		return "application/jsonl"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// Column is a field of types.Transaction named by its JSON tag, so all formats share names
+type Column struct {
+	Name string
+	Kind reflect.Kind
+	// field index in types.Transaction
+	index int
+}
+
+// Columns of exported transactions in field order
+var Columns = transactionColumns()
+
+func transactionColumns() []Column {
+	t := reflect.TypeOf(types.Transaction{})
+
+	columns := make([]Column, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+
+		kind := t.Field(i).Type.Kind()
+		// addresses are exported as checksummed strings
+		if t.Field(i).Type == reflect.TypeOf(types.Address{}) {
+			kind = reflect.String
+		}
+
+		columns = append(columns, Column{Name: name, Kind: kind, index: i})
+	}
+
+	return columns
+}
+
+// value returns field of tx as uint64, int64 or string, addresses are EIP-55 checksummed as in JSON
+func (c Column) value(tx types.Transaction) any {
+	v := reflect.ValueOf(tx).Field(c.index)
+
+	if address, ok := v.Interface().(types.Address); ok {
+		return address.Checksum()
+	}
+
+	switch c.Kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	default:
+		return v.String()
+	}
+}
+
+// Encoder writes transactions in export format, Close must be called to complete output.
+// CSV and JSONL rows are written as encoded, Parquet rows are buffered by row group.
+type Encoder interface {
+	Encode(tx types.Transaction) error
+	Close() error
+}
+
+func NewEncoder(w io.Writer, format Format) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder(w)
+	case FormatJSONL:
+		return &jsonlEncoder{encoder: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return newParquetEncoder(w), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+	}
+}
+
+// Export writes transactions of addresses to w row by row, only transactions of one address are read at once
+func Export(ctx context.Context, repo Repository, w io.Writer, format Format, chainID uint64, addresses []types.Address) error {
+	tw, err := NewTransactionWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// addresses without transactions are skipped
+		txs, _ := repo.GetTransactions(ctx, chainID, address)
+		for _, tx := range txs {
+			if err := tw.Write(tx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tw.Close()
+}
+
+// TransactionWriter encodes transactions as they are written, transaction written several times,
+// e.g. transfer between exported addresses, is encoded once
+type TransactionWriter struct {
+	encoder Encoder
+	written map[string]struct{}
+}
+
+func NewTransactionWriter(w io.Writer, format Format) (*TransactionWriter, error) {
+	encoder, err := NewEncoder(w, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionWriter{
+		encoder: encoder,
+		written: make(map[string]struct{}),
+	}, nil
+}
+
+func (tw *TransactionWriter) Write(tx types.Transaction) error {
+	if _, ok := tw.written[tx.Hash]; ok {
+		return nil
+	}
+	tw.written[tx.Hash] = struct{}{}
+
+	if err := tw.encoder.Encode(tx); err != nil {
+		return fmt.Errorf("failed to write transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Close completes output, e.g. writes Parquet footer
+func (tw *TransactionWriter) Close() error {
+	return tw.encoder.Close()
+}
+
+type jsonlEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *jsonlEncoder) Encode(tx types.Transaction) error {
+	return e.encoder.Encode(tx)
+}
+
+func (e *jsonlEncoder) Close() error {
+	return nil
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVEncoder(w io.Writer) (*csvEncoder, error) {
+	e := &csvEncoder{
+		w:      csv.NewWriter(w),
+		record: make([]string, len(Columns)),
+	}
+
+	for i, c := range Columns {
+		e.record[i] = c.Name
+	}
+
+	if err := e.w.Write(e.record); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *csvEncoder) Encode(tx types.Transaction) error {
+	for i, c := range Columns {
+		switch v := c.value(tx).(type) {
+		case uint64:
+			e.record[i] = strconv.FormatUint(v, 10)
+		case int64:
+			e.record[i] = strconv.FormatInt(v, 10)
+		case string:
+			e.record[i] = v
+		}
+	}
+
+	if err := e.w.Write(e.record); err != nil {
+		return err
+	}
+
+	// row is passed to underlying writer right away, it's buffered by caller if needed
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
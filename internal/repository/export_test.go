@@ -0,0 +1,234 @@
+package repository_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+var (
+	alice = types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	bob   = types.MustParseAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+)
+
+func Test_Export(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.New()
+
+	transfer := types.NewTransaction(1, "0x01", alice, bob, 1736000000)
+	creation := types.NewTransaction(1, "0x02", alice, types.Address{}, 1736000012)
+
+	repo.SaveTransactions(ctx, 1, alice, []types.Transaction{transfer, creation})
+	repo.SaveTransactions(ctx, 1, bob, []types.Transaction{transfer})
+
+	testCases := []struct {
+		desc   string
+		format string
+		// empty if output is checked by check
+		want  string
+		check func(t *testing.T, out []byte)
+	}{
+		{
+			desc:   "CSV",
+			format: "csv",
+			want: "chain_id,hash,from,to,timestamp\n" +
+				"1,0x01,0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed,0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359,1736000000\n" +
+				"1,0x02,0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed,,1736000012\n",
+		},
+		{
+			desc:   "JSONL",
+			format: "JSONL",
+			want: `{"chain_id":1,"hash":"0x01","from":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed","to":"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359","timestamp":1736000000}` + "\n" +
+				`{"chain_id":1,"hash":"0x02","from":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed","to":"","timestamp":1736000012}` + "\n",
+		},
+		{
+			desc:   "Parquet",
+			format: "parquet",
+			check: func(t *testing.T, out []byte) {
+				columns, rows := decodeParquet(t, out)
+
+				wantColumns := make([]string, 0, len(repository.Columns))
+				for _, c := range repository.Columns {
+					wantColumns = append(wantColumns, c.Name)
+				}
+
+				if !slices.Equal(columns, wantColumns) {
+					t.Fatalf("columns are not equal, want %v, got %v", wantColumns, columns)
+				}
+
+				wantRows := [][]any{
+					{int64(1), "0x01", alice.Checksum(), bob.Checksum(), int64(1736000000)},
+					{int64(1), "0x02", alice.Checksum(), "", int64(1736000012)},
+				}
+
+				if !reflect.DeepEqual(rows, wantRows) {
+					t.Fatalf("rows are not equal, want %v, got %v", wantRows, rows)
+				}
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			format, err := repository.ParseFormat(tC.format)
+			if err != nil {
+				t.Fatalf("failed to parse format: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := repository.Export(ctx, repo, &out, format, 1, []types.Address{alice, bob}); err != nil {
+				t.Fatalf("failed to export: %v", err)
+			}
+
+			if tC.check != nil {
+				tC.check(t, out.Bytes())
+				return
+			}
+
+			if out.String() != tC.want {
+				t.Fatalf("export is not equal, want\n%s\ngot\n%s", tC.want, out.String())
+			}
+		})
+	}
+}
+
+// decodeParquet reads column names from footer schema and rows from PLAIN encoded data pages of all row groups
+func decodeParquet(t *testing.T, out []byte) ([]string, [][]any) {
+	t.Helper()
+
+	if !bytes.HasPrefix(out, []byte("PAR1")) || !bytes.HasSuffix(out, []byte("PAR1")) {
+		t.Fatalf("parquet magic is missing")
+	}
+
+	size := int(binary.LittleEndian.Uint32(out[len(out)-8:]))
+	if size <= 0 || size > len(out)-12 {
+		t.Fatalf("invalid footer length %d of file size %d", size, len(out))
+	}
+
+	meta := (&thriftReader{buf: out[len(out)-8-size : len(out)-8]}).readStruct()
+
+	// root element is followed by columns
+	schema := meta[2].([]any)
+
+	var (
+		columns  []string
+		physical []int64
+	)
+	for _, el := range schema[1:] {
+		columns = append(columns, el.(map[int16]any)[4].(string))
+		physical = append(physical, el.(map[int16]any)[1].(int64))
+	}
+
+	rows := make([][]any, meta[3].(int64))
+	var next int
+
+	for _, rg := range meta[4].([]any) {
+		numRows := int(rg.(map[int16]any)[3].(int64))
+
+		for i, chunk := range rg.(map[int16]any)[1].([]any) {
+			offset := chunk.(map[int16]any)[3].(map[int16]any)[9].(int64)
+
+			r := &thriftReader{buf: out[offset:]}
+			header := r.readStruct()
+
+			if values := header[5].(map[int16]any)[1].(int64); int(values) != numRows {
+				t.Fatalf("values count of column %s is not equal, want %d, got %d", columns[i], numRows, values)
+			}
+
+			data := r.buf[r.pos : r.pos+int(header[2].(int64))]
+			for row := next; row < next+numRows; row++ {
+				if physical[i] == 2 {
+					rows[row] = append(rows[row], int64(binary.LittleEndian.Uint64(data)))
+					data = data[8:]
+					continue
+				}
+
+				n := binary.LittleEndian.Uint32(data)
+				rows[row] = append(rows[row], string(data[4:4+n]))
+				data = data[4+n:]
+			}
+		}
+
+		next += numRows
+	}
+
+	return columns, rows
+}
+
+// thriftReader decodes thrift compact protocol, integers are int64, binaries are strings,
+// structs are maps by field id and lists are slices
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+
+	var last int16
+	for {
+		b := r.buf[r.pos]
+		r.pos++
+
+		if b == 0 {
+			return fields
+		}
+
+		if delta := int16(b >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(r.varint())
+		}
+
+		fields[last] = r.read(b & 0x0f)
+	}
+}
+
+func (r *thriftReader) read(typ byte) any {
+	switch typ {
+	case 5, 6:
+		return r.varint()
+	case 8:
+		n := int(r.uvarint())
+		s := string(r.buf[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case 9:
+		header := r.buf[r.pos]
+		r.pos++
+
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+
+		list := make([]any, size)
+		for i := range list {
+			list[i] = r.read(header & 0x0f)
+		}
+		return list
+	case 12:
+		return r.readStruct()
+	default:
+		panic(fmt.Sprintf("unsupported thrift type %d", typ))
+	}
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Varint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	r.pos += n
+	return v
+}
@@ -0,0 +1,287 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/avelex/blockchain-parser/internal/fsutil"
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// record is a line of the log, either transactions of address, processed block or range, subscription
+// or batch of writes committed together
+type record struct {
+	ChainID      uint64                 `json:"chain_id"`
+	Address      *types.Address         `json:"address,omitempty"`
+	Transactions []types.Transaction    `json:"transactions,omitempty"`
+	Processed    *int                   `json:"processed,omitempty"`
+	Range        *repository.BlockRange `json:"range,omitempty"`
+	Subscription *types.Address         `json:"subscription,omitempty"`
+	Batch        *repository.Batch      `json:"batch,omitempty"`
+}
+
+// ErrReadOnly is returned by writes to repository opened by OpenReadOnly
+var ErrReadOnly = errors.New("repository is read-only")
+
+// Repository persists writes to append-only JSON lines log and serves reads from memory,
+// the log is replayed on open
+type Repository struct {
+	mu   *sync.Mutex
+	f    *os.File
+	lock *os.File
+	path string
+	mem  *memory.Repository
+	// transactions evicted since the log was rewritten
+	evicted int
+}
+
+// Open loads log from path, the file is created if missing.
+// Only one process may open the log for writing, the others fail with fsutil.ErrLocked
+func Open(path string) (*Repository, error) {
+	// the log itself is replaced on rewrite, so the lock is held on separate file
+	lock, err := fsutil.Lock(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	r, err := open(path)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	r.lock = lock
+
+	return r, nil
+}
+
+func open(path string) (*Repository, error) {
+	mem := memory.New()
+
+	valid, torn, err := replay(path, mem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	// next records would be appended to incomplete line otherwise
+	if torn {
+		slog.Warn("Truncated incomplete last line of repository log", "path", path, "size", valid)
+
+		if err := os.Truncate(path, valid); err != nil {
+			return nil, fmt.Errorf("failed to truncate %s: %w", path, err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	return &Repository{
+		mu:   &sync.Mutex{},
+		f:    f,
+		path: path,
+		mem:  mem,
+	}, nil
+}
+
+// OpenReadOnly loads log without modifying it, e.g. to query store written by running instance
+func OpenReadOnly(path string) (*Repository, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	mem := memory.New()
+
+	// incomplete last line may be still written by running instance
+	if _, _, err := replay(path, mem); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	return &Repository{
+		mu:   &sync.Mutex{},
+		path: path,
+		mem:  mem,
+	}, nil
+}
+
+// replay applies log records to mem, returns size of complete lines
+// and whether the last line is incomplete, e.g. write was interrupted by crash
+func replay(path string, mem *memory.Repository) (int64, bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+
+	var valid int64
+
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return valid, len(data) > 0, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+
+		var r record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return 0, false, fmt.Errorf("failed to parse line %d: %w", line, err)
+		}
+
+		if err := apply(context.Background(), mem, r); err != nil {
+			return 0, false, err
+		}
+
+		valid += int64(len(data))
+	}
+}
+
+func apply(ctx context.Context, mem *memory.Repository, r record) error {
+	switch {
+	case r.Batch != nil:
+		return mem.Commit(ctx, r.Batch)
+	case r.Processed != nil:
+		return mem.MarkProcessed(ctx, r.ChainID, *r.Processed)
+	case r.Range != nil:
+		return mem.MarkProcessedRange(ctx, r.ChainID, *r.Range)
+	case r.Subscription != nil:
+		return mem.SaveSubscription(ctx, r.ChainID, *r.Subscription)
+	case r.Address != nil:
+		return mem.SaveTransactions(ctx, r.ChainID, *r.Address, r.Transactions)
+	}
+	return nil
+}
+
+// append writes record as a single line, so it's either fully written or skipped on replay
+func (r *Repository) append(ctx context.Context, rec record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return ErrReadOnly
+	}
+
+	// transactions of reprocessed blocks are not written again
+	if rec.Address != nil {
+		rec.Transactions = r.mem.Unsaved(rec.ChainID, *rec.Address, rec.Transactions)
+		if len(rec.Transactions) == 0 {
+			return nil
+		}
+	}
+
+	if rec.Batch != nil {
+		rec.Batch = r.unsavedBatch(rec.Batch)
+		if len(rec.Batch.Transactions) == 0 && len(rec.Batch.Processed) == 0 && len(rec.Batch.Deleted) == 0 {
+			return nil
+		}
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	// failed write is truncated, so the next record is not appended to its torn line
+	if err := fsutil.AppendLine(r.f, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", r.path, err)
+	}
+
+	return apply(ctx, r.mem, rec)
+}
+
+func (r *Repository) GetTransactions(ctx context.Context, chainID uint64, address types.Address) ([]types.Transaction, error) {
+	return r.mem.GetTransactions(ctx, chainID, address)
+}
+
+func (r *Repository) SaveTransactions(ctx context.Context, chainID uint64, address types.Address, transactions []types.Transaction) error {
+	return r.append(ctx, record{ChainID: chainID, Address: &address, Transactions: transactions})
+}
+
+// Commit writes batch as a single line, torn line of interrupted write is dropped on open as a whole
+func (r *Repository) Commit(ctx context.Context, batch *repository.Batch) error {
+	return r.append(ctx, record{ChainID: batch.ChainID, Batch: batch})
+}
+
+// unsavedBatch returns copy of batch without saved transactions, batch of caller is not modified
+func (r *Repository) unsavedBatch(batch *repository.Batch) *repository.Batch {
+	unsaved := &repository.Batch{
+		ChainID:      batch.ChainID,
+		Transactions: make(map[types.Address][]types.Transaction, len(batch.Transactions)),
+		Processed:    batch.Processed,
+		Deleted:      batch.Deleted,
+	}
+
+	for address, txs := range batch.Transactions {
+		// deleted transactions may be saved again, e.g. included in block replacing the reorged one
+		if _, ok := batch.Deleted[address]; ok {
+			unsaved.Transactions[address] = txs
+			continue
+		}
+
+		if txs = r.mem.Unsaved(batch.ChainID, address, txs); len(txs) > 0 {
+			unsaved.Transactions[address] = txs
+		}
+	}
+
+	return unsaved
+}
+
+func (r *Repository) MarkProcessed(ctx context.Context, chainID uint64, number int) error {
+	return r.append(ctx, record{ChainID: chainID, Processed: &number})
+}
+
+func (r *Repository) MarkProcessedRange(ctx context.Context, chainID uint64, br repository.BlockRange) error {
+	return r.append(ctx, record{ChainID: chainID, Range: &br})
+}
+
+func (r *Repository) ProcessedRanges(ctx context.Context, chainID uint64) ([]repository.BlockRange, error) {
+	return r.mem.ProcessedRanges(ctx, chainID)
+}
+
+func (r *Repository) SaveSubscription(ctx context.Context, chainID uint64, address types.Address) error {
+	// addresses are subscribed again on every start, e.g. from webhook subscriptions
+	if r.mem.Subscribed(chainID, address) {
+		return nil
+	}
+	return r.append(ctx, record{ChainID: chainID, Subscription: &address})
+}
+
+func (r *Repository) Subscriptions(ctx context.Context, chainID uint64) ([]types.Address, error) {
+	return r.mem.Subscriptions(ctx, chainID)
+}
+
+func (r *Repository) Ping(ctx context.Context) error {
+	if _, err := os.Stat(r.path); err != nil {
+		return fmt.Errorf("failed to stat %s: %w", r.path, err)
+	}
+	return nil
+}
+
+func (r *Repository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return nil
+	}
+
+	err := r.f.Close()
+	r.lock.Close()
+
+	return err
+}
@@ -0,0 +1,101 @@
+package file_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/file"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+func Test_CommitFailed(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "transactions.jsonl")
+
+	repo, err := file.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	commit := func(hash string, number int) error {
+		batch := repository.NewBatch(1)
+		batch.SaveTransactions(address, []types.Transaction{{ChainID: 1, Hash: hash, From: address, Timestamp: 1736000000}})
+		batch.MarkProcessed(number)
+		return repo.Commit(ctx, batch)
+	}
+
+	if err := commit("0x01", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// file size limit makes the next write short, exceeding it is reported by error instead of signal
+	signal.Ignore(syscall.SIGXFSZ)
+	defer signal.Reset(syscall.SIGXFSZ)
+
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_FSIZE, &limit); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	short := syscall.Rlimit{Cur: uint64(info.Size()) + 20, Max: limit.Max}
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &short); err != nil {
+		t.Skipf("file size limit is not supported: %v", err)
+	}
+
+	err = commit("0x02", 11)
+
+	if err := syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit); err != nil {
+		t.Fatal(err)
+	}
+
+	if err == nil {
+		t.Fatalf("commit over file size limit must fail")
+	}
+
+	// the log goes on after failed write
+	if err := commit("0x03", 12); err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	reopened, err := file.Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen log with failed write in the middle: %v", err)
+	}
+	defer reopened.Close()
+
+	txs, err := reopened.GetTransactions(ctx, 1, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, tx := range txs {
+		got = append(got, tx.Hash)
+	}
+
+	if want := []string{"0x01", "0x03"}; !reflect.DeepEqual(want, got) {
+		t.Fatalf("transactions are not equal, want %v, got %v", want, got)
+	}
+
+	ranges, err := reopened.ProcessedRanges(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []repository.BlockRange{{From: 10, To: 10}, {From: 12, To: 12}}; !reflect.DeepEqual(want, ranges) {
+		t.Fatalf("processed ranges are not equal, want %v, got %v", want, ranges)
+	}
+}
@@ -0,0 +1,157 @@
+package file_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/fsutil"
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/file"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+var address = types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+
+func Test_Reopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "transactions.jsonl")
+
+	repo, err := file.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+
+	txs := []types.Transaction{{ChainID: 1, Hash: "0x01", From: address, Timestamp: 1736000000}}
+	if err := repo.SaveTransactions(ctx, 1, address, txs); err != nil {
+		t.Fatalf("failed to save transactions: %v", err)
+	}
+
+	for _, number := range []int{10, 12, 11, 15, 20, 14} {
+		if err := repo.MarkProcessed(ctx, 1, number); err != nil {
+			t.Fatalf("failed to mark block %d: %v", number, err)
+		}
+	}
+	repo.Close()
+
+	// write interrupted by crash
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	f.WriteString(`{"chain_id":1,"proc`)
+	f.Close()
+
+	readOnly, err := file.OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("failed to open read-only: %v", err)
+	}
+
+	if err := readOnly.MarkProcessed(ctx, 1, 21); !errors.Is(err, file.ErrReadOnly) {
+		t.Fatalf("error is not equal, want %v, got %v", file.ErrReadOnly, err)
+	}
+
+	repo, err = file.Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen: %v", err)
+	}
+	defer repo.Close()
+
+	// e.g. backfill while the instance is running
+	if _, err := file.Open(path); !errors.Is(err, fsutil.ErrLocked) {
+		t.Fatalf("error is not equal, want %v, got %v", fsutil.ErrLocked, err)
+	}
+
+	if err := repo.MarkProcessed(ctx, 1, 21); err != nil {
+		t.Fatalf("failed to mark block after reopen: %v", err)
+	}
+
+	got, err := repo.GetTransactions(ctx, 1, address)
+	if err != nil {
+		t.Fatalf("failed to get transactions: %v", err)
+	}
+
+	if !reflect.DeepEqual(txs, got) {
+		t.Fatalf("transactions are not equal, want %v, got %v", txs, got)
+	}
+
+	ranges, err := repo.ProcessedRanges(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get processed ranges: %v", err)
+	}
+
+	wantRanges := []repository.BlockRange{{From: 10, To: 12}, {From: 14, To: 15}, {From: 20, To: 21}}
+	if !reflect.DeepEqual(wantRanges, ranges) {
+		t.Fatalf("ranges are not equal, want %v, got %v", wantRanges, ranges)
+	}
+
+	wantGaps := []repository.BlockRange{{From: 13, To: 13}, {From: 16, To: 19}, {From: 22, To: 25}}
+	if gaps := repository.Gaps(ranges, 11, 25); !reflect.DeepEqual(wantGaps, gaps) {
+		t.Fatalf("gaps are not equal, want %v, got %v", wantGaps, gaps)
+	}
+}
+
+func Test_CommitInterrupted(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "transactions.jsonl")
+
+	repo, err := file.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+
+	committed := repository.NewBatch(1)
+	committed.SaveTransactions(address, []types.Transaction{{ChainID: 1, Hash: "0x01", From: address, Timestamp: 1736000000}})
+	committed.MarkProcessed(10)
+
+	if err := repo.Commit(ctx, committed); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat log: %v", err)
+	}
+
+	interrupted := repository.NewBatch(1)
+	interrupted.SaveTransactions(address, []types.Transaction{{ChainID: 1, Hash: "0x02", To: address, Timestamp: 1736000012}})
+	interrupted.MarkProcessed(11)
+
+	if err := repo.Commit(ctx, interrupted); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	repo.Close()
+
+	// crash in the middle of writing the second batch
+	if err := os.Truncate(path, stat.Size()+20); err != nil {
+		t.Fatalf("failed to truncate log: %v", err)
+	}
+
+	repo, err = file.Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen: %v", err)
+	}
+	defer repo.Close()
+
+	got, err := repo.GetTransactions(ctx, 1, address)
+	if err != nil {
+		t.Fatalf("failed to get transactions: %v", err)
+	}
+
+	if !reflect.DeepEqual(committed.Transactions[address], got) {
+		t.Fatalf("transactions are not equal, want %v, got %v", committed.Transactions[address], got)
+	}
+
+	ranges, err := repo.ProcessedRanges(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get processed ranges: %v", err)
+	}
+
+	wantRanges := []repository.BlockRange{{From: 10, To: 10}}
+	if !reflect.DeepEqual(wantRanges, ranges) {
+		t.Fatalf("ranges are not equal, want %v, got %v", wantRanges, ranges)
+	}
+}
@@ -0,0 +1,97 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/fsutil"
+	"github.com/avelex/blockchain-parser/internal/repository"
+)
+
+// rewriteRatio is evicted to kept transactions ratio, when reached the log is rewritten without evicted records
+const rewriteRatio = 4
+
+// Prune evicts transactions from memory, evicted records are dropped from the log when it's rewritten.
+// The log is rewritten once evicted transactions make up a significant part of it, not on every call.
+func (r *Repository) Prune(ctx context.Context, retention repository.Retention, now time.Time) (repository.PruneStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return repository.PruneStats{}, ErrReadOnly
+	}
+
+	stats, err := r.mem.Prune(ctx, retention, now)
+	if err != nil {
+		return stats, err
+	}
+
+	r.evicted += stats.Evicted()
+	if r.evicted == 0 || r.evicted*rewriteRatio < stats.Transactions {
+		return stats, nil
+	}
+
+	if err := r.rewrite(ctx); err != nil {
+		return stats, err
+	}
+	r.evicted = 0
+
+	return stats, nil
+}
+
+// rewrite replaces the log with records of current state and reopens it for appending
+func (r *Repository) rewrite(ctx context.Context) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, chainID := range r.mem.ChainIDs() {
+		subscriptions, err := r.mem.Subscriptions(ctx, chainID)
+		if err != nil {
+			return err
+		}
+		for _, address := range subscriptions {
+			if err := encoder.Encode(record{ChainID: chainID, Subscription: &address}); err != nil {
+				return fmt.Errorf("failed to marshal record: %w", err)
+			}
+		}
+
+		for _, address := range r.mem.Addresses(chainID) {
+			txs, err := r.mem.GetTransactions(ctx, chainID, address)
+			if err != nil {
+				return err
+			}
+			if err := encoder.Encode(record{ChainID: chainID, Address: &address, Transactions: txs}); err != nil {
+				return fmt.Errorf("failed to marshal record: %w", err)
+			}
+		}
+
+		ranges, err := r.mem.ProcessedRanges(ctx, chainID)
+		if err != nil {
+			return err
+		}
+		for _, br := range ranges {
+			if err := encoder.Encode(record{ChainID: chainID, Range: &br}); err != nil {
+				return fmt.Errorf("failed to marshal record: %w", err)
+			}
+		}
+	}
+
+	if err := fsutil.WriteFileAtomic(r.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", r.path, err)
+	}
+
+	// file was replaced, old descriptor points to the removed one
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", r.path, err)
+	}
+
+	r.f.Close()
+	r.f = f
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package file_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/file"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+func Test_Prune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1736000000, 0)
+
+	testCases := []struct {
+		desc            string
+		maxTransactions int
+		wantRewritten   bool
+		// transactions of address after reopen
+		wantReopened int
+	}{
+		{
+			desc:            "Below rewrite ratio",
+			maxTransactions: 9,
+			// evicted records stay in the log until it's rewritten
+			wantReopened: 10,
+		},
+		{
+			desc:            "Log is rewritten",
+			maxTransactions: 2,
+			wantRewritten:   true,
+			wantReopened:    2,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "transactions.jsonl")
+
+			repo, err := file.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { repo.Close() }()
+
+			for i := 0; i < 10; i++ {
+				tx := types.NewTransaction(1, fmt.Sprintf("0x%02d", i), address, types.Address{}, now.Unix()+int64(i))
+				if err := repo.SaveTransactions(ctx, 1, address, []types.Transaction{tx}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := repo.SaveSubscription(ctx, 1, address); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.MarkProcessedRange(ctx, 1, repository.BlockRange{From: 1, To: 10}); err != nil {
+				t.Fatal(err)
+			}
+
+			before := fileSize(t, path)
+
+			retention := repository.NewRetention(repository.RetentionPolicy{MaxTransactions: tC.maxTransactions}, nil, 0)
+			if _, err := repo.Prune(ctx, retention, now); err != nil {
+				t.Fatal(err)
+			}
+
+			if rewritten := fileSize(t, path) < before; rewritten != tC.wantRewritten {
+				t.Fatalf("rewritten is not equal, want %v, got %v", tC.wantRewritten, rewritten)
+			}
+
+			// rewritten log is reopened for appending
+			if err := repo.MarkProcessed(ctx, 1, 11); err != nil {
+				t.Fatal(err)
+			}
+			repo.Close()
+
+			repo, err = file.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			txs, err := repo.GetTransactions(ctx, 1, address)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(txs) != tC.wantReopened || txs[len(txs)-1].Hash != "0x09" {
+				t.Fatalf("transactions are not equal, want %d up to 0x09, got %v", tC.wantReopened, txs)
+			}
+
+			subscriptions, err := repo.Subscriptions(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ranges, err := repo.ProcessedRanges(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []repository.BlockRange{{From: 1, To: 11}}
+			if len(subscriptions) != 1 || len(ranges) != 1 || ranges[0] != want[0] {
+				t.Fatalf("state is not equal, want 1 subscription and ranges %v, got %v and %v", want, subscriptions, ranges)
+			}
+		})
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
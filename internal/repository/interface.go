@@ -2,11 +2,65 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/avelex/blockchain-parser/internal/types"
 )
 
+// Repository stores transactions by chain ID and address
 type Repository interface {
-	GetTransactions(ctx context.Context, address string) ([]types.Transaction, error)
-	SaveTransactions(ctx context.Context, address string, transactions []types.Transaction) error
+	GetTransactions(ctx context.Context, chainID uint64, address types.Address) ([]types.Transaction, error)
+	SaveTransactions(ctx context.Context, chainID uint64, address types.Address, transactions []types.Transaction) error
+	// record block as processed, used to find gaps in parsed ranges
+	MarkProcessed(ctx context.Context, chainID uint64, number int) error
+	// record range of blocks as processed, e.g. restored from snapshot
+	MarkProcessedRange(ctx context.Context, chainID uint64, r BlockRange) error
+	// apply all writes of batch or none of them
+	Commit(ctx context.Context, batch *Batch) error
+	// sorted ranges of processed blocks
+	ProcessedRanges(ctx context.Context, chainID uint64) ([]BlockRange, error)
+	// persist address subscribed on chain, repeated saves are ignored
+	SaveSubscription(ctx context.Context, chainID uint64, address types.Address) error
+	// sorted addresses subscribed on chain
+	Subscriptions(ctx context.Context, chainID uint64) ([]types.Address, error)
+	// evict transactions exceeding retention limits, now is the reference time of max age
+	Prune(ctx context.Context, retention Retention, now time.Time) (PruneStats, error)
+	// check repository is reachable
+	Ping(ctx context.Context) error
+}
+
+// BlockRange is inclusive range of block numbers
+type BlockRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (r BlockRange) Len() int {
+	return r.To - r.From + 1
+}
+
+// Gaps returns ranges missing in sorted ranges between from and to inclusive
+func Gaps(ranges []BlockRange, from, to int) []BlockRange {
+	var gaps []BlockRange
+
+	next := from
+	for _, r := range ranges {
+		if r.To < next {
+			continue
+		}
+		if r.From > to {
+			break
+		}
+
+		if r.From > next {
+			gaps = append(gaps, BlockRange{From: next, To: r.From - 1})
+		}
+		next = r.To + 1
+	}
+
+	if next <= to {
+		gaps = append(gaps, BlockRange{From: next, To: to})
+	}
+
+	return gaps
 }
@@ -0,0 +1,22 @@
+package memory
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+)
+
+// addRange inserts block range into sorted ranges merging overlapping and adjacent ones
+func addRange(ranges []repository.BlockRange, r repository.BlockRange) []repository.BlockRange {
+	// first range touching r and the first range after it
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].To >= r.From-1 })
+	j := sort.Search(len(ranges), func(j int) bool { return ranges[j].From > r.To+1 })
+
+	if i < j {
+		r.From = min(r.From, ranges[i].From)
+		r.To = max(r.To, ranges[j-1].To)
+	}
+
+	return slices.Replace(ranges, i, j, r)
+}
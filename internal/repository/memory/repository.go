@@ -3,28 +3,45 @@ package memory
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
 
+	"github.com/avelex/blockchain-parser/internal/repository"
 	"github.com/avelex/blockchain-parser/internal/types"
 )
 
+type key struct {
+	chainID uint64
+	address types.Address
+}
+
 type Repository struct {
 	mu          *sync.RWMutex
-	subscribers map[string][]types.Transaction
+	subscribers map[key][]types.Transaction
+	// hashes of saved transactions, each transaction is saved once per address
+	hashes    map[key]map[string]struct{}
+	processed map[uint64][]repository.BlockRange
+	// subscribed addresses by chain ID
+	subscriptions map[uint64]map[types.Address]struct{}
 }
 
 func New() *Repository {
 	return &Repository{
 		mu:          &sync.RWMutex{},
-		subscribers: make(map[string][]types.Transaction),
+		subscribers: make(map[key][]types.Transaction),
+		hashes:      make(map[key]map[string]struct{}),
+		processed:   make(map[uint64][]repository.BlockRange),
+
+		subscriptions: make(map[uint64]map[types.Address]struct{}),
 	}
 }
 
-func (r *Repository) GetTransactions(ctx context.Context, address string) ([]types.Transaction, error) {
+func (r *Repository) GetTransactions(ctx context.Context, chainID uint64, address types.Address) ([]types.Transaction, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	tx, ok := r.subscribers[address]
+	tx, ok := r.subscribers[key{chainID, address}]
 	if !ok {
 		return nil, fmt.Errorf("address not found")
 	}
@@ -32,11 +49,149 @@ func (r *Repository) GetTransactions(ctx context.Context, address string) ([]typ
 	return tx, nil
 }
 
-func (r *Repository) SaveTransactions(ctx context.Context, address string, transactions []types.Transaction) error {
+// SaveTransactions appends transactions not saved for address yet, so reprocessed blocks are not duplicated.
+// Transactions are identified by hash, repository stores only transactions themselves, not their logs.
+func (r *Repository) SaveTransactions(ctx context.Context, chainID uint64, address types.Address, transactions []types.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.saveTransactions(key{chainID, address}, transactions)
+
+	return nil
+}
+
+// Commit applies batch under single lock, so readers see either all its writes or none
+func (r *Repository) Commit(ctx context.Context, batch *repository.Batch) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.subscribers[address] = append(r.subscribers[address], transactions...)
+	for address, hashes := range batch.Deleted {
+		r.deleteTransactions(key{batch.ChainID, address}, hashes)
+	}
+
+	for address, txs := range batch.Transactions {
+		r.saveTransactions(key{batch.ChainID, address}, txs)
+	}
+
+	for _, br := range batch.Processed {
+		r.processed[batch.ChainID] = addRange(r.processed[batch.ChainID], br)
+	}
+
+	return nil
+}
+
+func (r *Repository) deleteTransactions(k key, hashes []string) {
+	txs, ok := r.subscribers[k]
+	if !ok {
+		return
+	}
+
+	// slices returned by GetTransactions are shared with callers, so kept ones are copied
+	kept := slices.DeleteFunc(slices.Clone(txs), func(tx types.Transaction) bool {
+		return slices.Contains(hashes, tx.Hash)
+	})
+
+	if len(kept) != len(txs) {
+		r.replace(k, kept)
+	}
+}
+
+func (r *Repository) saveTransactions(k key, transactions []types.Transaction) {
+	transactions = r.unsaved(k, transactions)
+	if len(transactions) == 0 {
+		return
+	}
+
+	if r.hashes[k] == nil {
+		r.hashes[k] = make(map[string]struct{}, len(transactions))
+	}
+	for _, tx := range transactions {
+		r.hashes[k][tx.Hash] = struct{}{}
+	}
+
+	r.subscribers[k] = append(r.subscribers[k], transactions...)
+}
+
+// Unsaved returns transactions not saved for address, repeated ones are returned once
+func (r *Repository) Unsaved(chainID uint64, address types.Address, transactions []types.Transaction) []types.Transaction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.unsaved(key{chainID, address}, transactions)
+}
+
+func (r *Repository) unsaved(k key, transactions []types.Transaction) []types.Transaction {
+	saved := r.hashes[k]
+	unsaved := make([]types.Transaction, 0, len(transactions))
+	seen := make(map[string]struct{}, len(transactions))
+
+	for _, tx := range transactions {
+		if _, ok := saved[tx.Hash]; ok {
+			continue
+		}
+		if _, ok := seen[tx.Hash]; ok {
+			continue
+		}
+		seen[tx.Hash] = struct{}{}
+		unsaved = append(unsaved, tx)
+	}
+
+	return unsaved
+}
+
+func (r *Repository) MarkProcessed(ctx context.Context, chainID uint64, number int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.processed[chainID] = addRange(r.processed[chainID], repository.BlockRange{From: number, To: number})
+
+	return nil
+}
+
+func (r *Repository) MarkProcessedRange(ctx context.Context, chainID uint64, br repository.BlockRange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.processed[chainID] = addRange(r.processed[chainID], br)
+
+	return nil
+}
+
+func (r *Repository) ProcessedRanges(ctx context.Context, chainID uint64) ([]repository.BlockRange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.processed[chainID]), nil
+}
+
+func (r *Repository) SaveSubscription(ctx context.Context, chainID uint64, address types.Address) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.subscriptions[chainID] == nil {
+		r.subscriptions[chainID] = make(map[types.Address]struct{})
+	}
+	r.subscriptions[chainID][address] = struct{}{}
+
+	return nil
+}
+
+// Subscribed reports whether address is saved as subscribed on chain
+func (r *Repository) Subscribed(chainID uint64, address types.Address) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.subscriptions[chainID][address]
+	return ok
+}
+
+func (r *Repository) Subscriptions(ctx context.Context, chainID uint64) ([]types.Address, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.SortedFunc(maps.Keys(r.subscriptions[chainID]), types.Address.Compare), nil
+}
 
+func (r *Repository) Ping(ctx context.Context) error {
 	return nil
 }
@@ -0,0 +1,106 @@
+package memory_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+var address = types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+
+func Test_Commit(t *testing.T) {
+	ctx := context.Background()
+
+	stale := types.Transaction{ChainID: 1, Hash: "0x01", From: address, Timestamp: 1736000000}
+	replacement := types.Transaction{ChainID: 1, Hash: "0x02", From: address, Timestamp: 1736000012}
+
+	testCases := []struct {
+		desc       string
+		batch      func() *repository.Batch
+		wantHashes []string
+		wantRanges []repository.BlockRange
+	}{
+		{
+			desc:       "Empty batch",
+			batch:      func() *repository.Batch { return repository.NewBatch(1) },
+			wantHashes: []string{"0x01"},
+			wantRanges: []repository.BlockRange{{From: 10, To: 10}},
+		},
+		{
+			desc: "Saved transactions and processed block",
+			batch: func() *repository.Batch {
+				b := repository.NewBatch(1)
+				b.SaveTransactions(address, []types.Transaction{stale, replacement})
+				b.MarkProcessed(11)
+				return b
+			},
+			// saved transaction is not duplicated
+			wantHashes: []string{"0x01", "0x02"},
+			wantRanges: []repository.BlockRange{{From: 10, To: 11}},
+		},
+		{
+			desc: "Stale transactions deleted before saving",
+			batch: func() *repository.Batch {
+				b := repository.NewBatch(1)
+				b.DeleteTransactions(address, []types.Transaction{stale})
+				b.SaveTransactions(address, []types.Transaction{replacement})
+				b.MarkProcessed(10)
+				return b
+			},
+			wantHashes: []string{"0x02"},
+			wantRanges: []repository.BlockRange{{From: 10, To: 10}},
+		},
+		{
+			desc: "Another chain",
+			batch: func() *repository.Batch {
+				b := repository.NewBatch(10)
+				b.DeleteTransactions(address, []types.Transaction{stale})
+				b.MarkProcessed(20)
+				return b
+			},
+			wantHashes: []string{"0x01"},
+			wantRanges: []repository.BlockRange{{From: 10, To: 10}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			repo := memory.New()
+
+			initial := repository.NewBatch(1)
+			initial.SaveTransactions(address, []types.Transaction{stale})
+			initial.MarkProcessed(10)
+
+			if err := repo.Commit(ctx, initial); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := repo.Commit(ctx, tC.batch()); err != nil {
+				t.Fatal(err)
+			}
+
+			txs, _ := repo.GetTransactions(ctx, 1, address)
+
+			var hashes []string
+			for _, tx := range txs {
+				hashes = append(hashes, tx.Hash)
+			}
+
+			if !reflect.DeepEqual(tC.wantHashes, hashes) {
+				t.Fatalf("transactions are not equal, want %v, got %v", tC.wantHashes, hashes)
+			}
+
+			ranges, err := repo.ProcessedRanges(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tC.wantRanges, ranges) {
+				t.Fatalf("processed ranges are not equal, want %v, got %v", tC.wantRanges, ranges)
+			}
+		})
+	}
+}
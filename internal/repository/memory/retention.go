@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// Prune evicts transactions older than max age and all but the latest saved max transactions of address,
+// then the oldest transactions of all addresses until their size fits max bytes
+func (r *Repository) Prune(ctx context.Context, retention repository.Retention, now time.Time) (repository.PruneStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stats repository.PruneStats
+
+	for k, txs := range r.subscribers {
+		policy := retention.Policy(k.address)
+		kept := txs
+
+		if policy.MaxAge > 0 {
+			cutoff := now.Add(-policy.MaxAge).Unix()
+			// slices returned by GetTransactions are shared with callers, so kept ones are copied
+			kept = slices.DeleteFunc(slices.Clone(kept), func(tx types.Transaction) bool {
+				return tx.Timestamp < cutoff
+			})
+			stats.EvictedByAge += len(txs) - len(kept)
+		}
+
+		if policy.MaxTransactions > 0 && len(kept) > policy.MaxTransactions {
+			stats.EvictedByCount += len(kept) - policy.MaxTransactions
+			kept = slices.Clone(kept[len(kept)-policy.MaxTransactions:])
+		}
+
+		if len(kept) != len(txs) {
+			r.replace(k, kept)
+		}
+	}
+
+	if retention.MaxBytes > 0 {
+		stats.EvictedBySize = r.pruneSize(retention.MaxBytes)
+	}
+
+	for _, txs := range r.subscribers {
+		stats.Transactions += len(txs)
+		for _, tx := range txs {
+			stats.Bytes += repository.TransactionSize(tx)
+		}
+	}
+
+	return stats, nil
+}
+
+// pruneSize evicts the oldest transactions until size of all transactions fits max bytes
+func (r *Repository) pruneSize(maxBytes int64) int {
+	type entry struct {
+		key       key
+		index     int
+		timestamp int64
+		size      int64
+	}
+
+	var (
+		entries []entry
+		total   int64
+	)
+
+	for k, txs := range r.subscribers {
+		for i, tx := range txs {
+			size := repository.TransactionSize(tx)
+			entries = append(entries, entry{key: k, index: i, timestamp: tx.Timestamp, size: size})
+			total += size
+		}
+	}
+
+	if total <= maxBytes {
+		return 0
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].timestamp < entries[j].timestamp
+	})
+
+	evicted := make(map[key]map[int]struct{})
+
+	var n int
+	for ; n < len(entries) && total > maxBytes; n++ {
+		e := entries[n]
+		if evicted[e.key] == nil {
+			evicted[e.key] = make(map[int]struct{})
+		}
+		evicted[e.key][e.index] = struct{}{}
+		total -= e.size
+	}
+
+	for k, indexes := range evicted {
+		txs := r.subscribers[k]
+
+		kept := make([]types.Transaction, 0, len(txs)-len(indexes))
+		for i, tx := range txs {
+			if _, ok := indexes[i]; !ok {
+				kept = append(kept, tx)
+			}
+		}
+
+		r.replace(k, kept)
+	}
+
+	return n
+}
+
+// replace sets transactions of key, address without transactions is removed.
+// Evicted transactions are forgotten, so they are saved again if their block is reprocessed.
+func (r *Repository) replace(k key, txs []types.Transaction) {
+	if len(txs) == 0 {
+		delete(r.subscribers, k)
+		delete(r.hashes, k)
+		return
+	}
+
+	hashes := make(map[string]struct{}, len(txs))
+	for _, tx := range txs {
+		hashes[tx.Hash] = struct{}{}
+	}
+
+	r.subscribers[k] = txs
+	r.hashes[k] = hashes
+}
+
+// ChainIDs returns sorted chains with transactions, processed blocks or subscriptions
+func (r *Repository) ChainIDs() []uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := make(map[uint64]struct{})
+	for k := range r.subscribers {
+		set[k.chainID] = struct{}{}
+	}
+	for chainID := range r.processed {
+		set[chainID] = struct{}{}
+	}
+	for chainID := range r.subscriptions {
+		set[chainID] = struct{}{}
+	}
+
+	ids := make([]uint64, 0, len(set))
+	for chainID := range set {
+		ids = append(ids, chainID)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// Addresses returns sorted addresses with transactions on chain
+func (r *Repository) Addresses(chainID uint64) []types.Address {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var addresses []types.Address
+	for k := range r.subscribers {
+		if k.chainID == chainID {
+			addresses = append(addresses, k.address)
+		}
+	}
+	slices.SortFunc(addresses, types.Address.Compare)
+
+	return addresses
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// Minimal Parquet writer without external dependencies: required columns, PLAIN encoding, no compression,
+// one data page per column chunk, see https://github.com/apache/parquet-format
+
+const (
+	parquetMagic = "PAR1"
+	// rows buffered before row group is written
+	parquetRowGroupSize = 64 * 1024
+	parquetCreatedBy    = "blockchain-parser"
+)
+
+// parquet physical types, encodings and converted types
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetPlain    = 0
+	parquetRLE      = 3
+
+	parquetUTF8   = 0
+	parquetUint64 = 14
+	parquetInt64C = 18
+
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+type parquetEncoder struct {
+	w      io.Writer
+	offset int64
+	err    error
+
+	// PLAIN encoded values of current row group by column
+	columns   []bytes.Buffer
+	rows      int
+	totalRows int64
+	rowGroups [][]byte
+}
+
+func newParquetEncoder(w io.Writer) *parquetEncoder {
+	return &parquetEncoder{
+		w:       w,
+		columns: make([]bytes.Buffer, len(Columns)),
+	}
+}
+
+func (e *parquetEncoder) Encode(tx types.Transaction) error {
+	for i, c := range Columns {
+		buf := &e.columns[i]
+
+		switch v := c.value(tx).(type) {
+		case uint64:
+			buf.Write(binary.LittleEndian.AppendUint64(buf.AvailableBuffer(), v))
+		case int64:
+			buf.Write(binary.LittleEndian.AppendUint64(buf.AvailableBuffer(), uint64(v)))
+		case string:
+			buf.Write(binary.LittleEndian.AppendUint32(buf.AvailableBuffer(), uint32(len(v))))
+			buf.WriteString(v)
+		}
+	}
+
+	e.rows++
+	if e.rows == parquetRowGroupSize {
+		return e.flushRowGroup()
+	}
+
+	return nil
+}
+
+func (e *parquetEncoder) Close() error {
+	if e.rows > 0 {
+		if err := e.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	if e.offset == 0 {
+		e.write([]byte(parquetMagic))
+	}
+
+	footer := e.fileMetaData()
+	e.write(footer)
+	e.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	e.write([]byte(parquetMagic))
+
+	return e.err
+}
+
+func (e *parquetEncoder) write(p []byte) {
+	if e.err != nil {
+		return
+	}
+
+	n, err := e.w.Write(p)
+	e.offset += int64(n)
+	e.err = err
+}
+
+// flushRowGroup writes buffered column chunks and keeps their metadata for footer
+func (e *parquetEncoder) flushRowGroup() error {
+	if e.offset == 0 {
+		e.write([]byte(parquetMagic))
+	}
+
+	rg := &thriftWriter{}
+	rg.listBegin(1, thriftStruct, len(Columns))
+
+	var groupSize int64
+
+	for i, c := range Columns {
+		data := e.columns[i].Bytes()
+
+		header := &thriftWriter{}
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(len(data)))
+		header.structBegin(5)
+		header.i32(1, int32(e.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.structEnd()
+		header.stop()
+
+		pageOffset := e.offset
+		chunkSize := int64(len(header.buf) + len(data))
+		groupSize += chunkSize
+
+		e.write(header.buf)
+		e.write(data)
+
+		// ColumnChunk
+		rg.listStructBegin()
+		rg.i64(2, pageOffset)
+		rg.structBegin(3)
+		rg.i32(1, parquetType(c))
+		rg.listBegin(2, thriftI32, 1)
+		rg.listI32(parquetPlain)
+		rg.listBegin(3, thriftBinary, 1)
+		rg.listBinary(c.Name)
+		rg.i32(4, parquetUncompressed)
+		rg.i64(5, int64(e.rows))
+		rg.i64(6, chunkSize)
+		rg.i64(7, chunkSize)
+		rg.i64(9, pageOffset)
+		rg.structEnd()
+		rg.listStructEnd()
+
+		e.columns[i].Reset()
+	}
+
+	rg.i64(2, groupSize)
+	rg.i64(3, int64(e.rows))
+	rg.stop()
+
+	e.rowGroups = append(e.rowGroups, rg.buf)
+	e.totalRows += int64(e.rows)
+	e.rows = 0
+
+	if e.err != nil {
+		return fmt.Errorf("failed to write row group: %w", e.err)
+	}
+
+	return nil
+}
+
+func (e *parquetEncoder) fileMetaData() []byte {
+	m := &thriftWriter{}
+	m.i32(1, 1)
+
+	// schema is flat, root element is followed by columns
+	m.listBegin(2, thriftStruct, len(Columns)+1)
+	m.listStructBegin()
+	m.binary(4, "schema")
+	m.i32(5, int32(len(Columns)))
+	m.listStructEnd()
+
+	for _, c := range Columns {
+		m.listStructBegin()
+		m.i32(1, parquetType(c))
+		m.i32(3, parquetRequired)
+		m.binary(4, c.Name)
+		m.i32(6, parquetConvertedType(c))
+		m.listStructEnd()
+	}
+
+	m.i64(3, e.totalRows)
+
+	m.listBegin(4, thriftStruct, len(e.rowGroups))
+	for _, rg := range e.rowGroups {
+		// row groups are complete structs
+		m.buf = append(m.buf, rg...)
+	}
+
+	m.binary(6, parquetCreatedBy)
+	m.stop()
+
+	return m.buf
+}
+
+func parquetType(c Column) int32 {
+	if c.Kind == reflect.String {
+		return parquetByteArray
+	}
+	return parquetInt64
+}
+
+func parquetConvertedType(c Column) int32 {
+	switch c.Kind {
+	case reflect.String:
+		return parquetUTF8
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return parquetUint64
+	default:
+		return parquetInt64C
+	}
+}
+
+// thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with thrift compact protocol, fields must be written in ascending order
+type thriftWriter struct {
+	buf []byte
+	// last field id of current struct and enclosing ones
+	last  int16
+	stack []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.listBinary(s)
+}
+
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.listStructBegin()
+}
+
+func (t *thriftWriter) structEnd() {
+	t.listStructEnd()
+}
+
+func (t *thriftWriter) listBegin(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elem)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elem)
+	t.buf = binary.AppendUvarint(t.buf, uint64(size))
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) listBinary(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// listStructBegin starts nested struct, its field ids are counted from zero
+func (t *thriftWriter) listStructBegin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) listStructEnd() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+func (t *thriftWriter) stop() {
+	t.buf = append(t.buf, 0)
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/metrics"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+var (
+	evictedTransactions = metrics.NewCounterVec("repository_evicted_transactions_total",
+		"Number of transactions evicted by retention policies.", "reason")
+	storedTransactions = metrics.NewGauge("repository_transactions", "Number of stored transactions.")
+	storedBytes        = metrics.NewGauge("repository_size_bytes", "Estimated size of stored transactions.")
+)
+
+// RetentionPolicy limits transactions of address, zero limit is disabled
+type RetentionPolicy struct {
+	MaxAge          time.Duration
+	MaxTransactions int
+}
+
+// Retention is a set of limits enforced by Prune
+type Retention struct {
+	Default RetentionPolicy
+	// policies of specific addresses, zero limit is taken from default policy
+	Addresses map[types.Address]RetentionPolicy
+	// estimated size of all transactions, see TransactionSize
+	MaxBytes int64
+}
+
+// NewRetention creates retention with default policy, policies of specific addresses and max bytes of all transactions
+func NewRetention(policy RetentionPolicy, addresses map[types.Address]RetentionPolicy, maxBytes int64) Retention {
+	if addresses == nil {
+		addresses = make(map[types.Address]RetentionPolicy)
+	}
+
+	return Retention{
+		Default:   policy,
+		Addresses: addresses,
+		MaxBytes:  maxBytes,
+	}
+}
+
+// Enabled reports whether any limit is set
+func (r Retention) Enabled() bool {
+	if r.Default != (RetentionPolicy{}) || r.MaxBytes > 0 {
+		return true
+	}
+
+	for _, p := range r.Addresses {
+		if p != (RetentionPolicy{}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Policy returns limits of address
+func (r Retention) Policy(address types.Address) RetentionPolicy {
+	p, ok := r.Addresses[address]
+	if !ok {
+		return r.Default
+	}
+
+	if p.MaxAge == 0 {
+		p.MaxAge = r.Default.MaxAge
+	}
+	if p.MaxTransactions == 0 {
+		p.MaxTransactions = r.Default.MaxTransactions
+	}
+
+	return p
+}
+
+// PruneStats are numbers of evicted transactions by reason and size of kept ones
+type PruneStats struct {
+	EvictedByAge   int
+	EvictedByCount int
+	EvictedBySize  int
+
+	Transactions int
+	Bytes        int64
+}
+
+func (s PruneStats) Evicted() int {
+	return s.EvictedByAge + s.EvictedByCount + s.EvictedBySize
+}
+
+var transactionStructSize = int64(reflect.TypeOf(types.Transaction{}).Size())
+
+// TransactionSize estimates memory used by stored transaction
+func TransactionSize(tx types.Transaction) int64 {
+	return transactionStructSize + int64(len(tx.Hash)+addressSize(tx.From)+addressSize(tx.To))
+}
+
+// addressSize is size of lowercase and checksum encodings kept by address
+func addressSize(a types.Address) int {
+	return len(a.Hex()) + len(a.Checksum())
+}
+
+// Compactor periodically enforces retention of repository
+type Compactor struct {
+	repo      Repository
+	retention Retention
+	interval  time.Duration
+}
+
+func NewCompactor(repo Repository, retention Retention, interval time.Duration) *Compactor {
+	return &Compactor{
+		repo:      repo,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Start prunes repository right away and then every interval until ctx is done
+func (c *Compactor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.compact(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Compactor) compact(ctx context.Context) {
+	start := time.Now()
+
+	stats, err := c.repo.Prune(ctx, c.retention, start)
+	if err != nil {
+		slog.Error("failed to prune repository", "error", err)
+		return
+	}
+
+	evictedTransactions.WithLabelValues("age").Add(float64(stats.EvictedByAge))
+	evictedTransactions.WithLabelValues("count").Add(float64(stats.EvictedByCount))
+	evictedTransactions.WithLabelValues("size").Add(float64(stats.EvictedBySize))
+	storedTransactions.Set(float64(stats.Transactions))
+	storedBytes.Set(float64(stats.Bytes))
+
+	if stats.Evicted() > 0 {
+		slog.Info("Pruned repository", "by_age", stats.EvictedByAge, "by_count", stats.EvictedByCount, "by_size", stats.EvictedBySize,
+			"transactions", stats.Transactions, "bytes", stats.Bytes, "dur", time.Since(start))
+	}
+}
@@ -0,0 +1,110 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+func Test_Prune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1736000000, 0)
+
+	// 10 transactions of each address, one per hour, the latest one of alice is an hour old
+	// and of bob an hour and a half
+	latest := map[types.Address]time.Time{
+		alice: now.Add(-time.Hour),
+		bob:   now.Add(-time.Hour - 30*time.Minute),
+	}
+
+	newRepo := func() *memory.Repository {
+		repo := memory.New()
+		for address, last := range latest {
+			txs := make([]types.Transaction, 0, 10)
+			for i := 9; i >= 0; i-- {
+				hash := fmt.Sprintf("0x%s%02d", address.Hex()[2:6], i)
+				txs = append(txs, types.NewTransaction(1, hash, address, types.Address{}, last.Add(time.Duration(-i)*time.Hour).Unix()))
+			}
+			repo.SaveTransactions(ctx, 1, address, txs)
+		}
+		return repo
+	}
+
+	txs, _ := newRepo().GetTransactions(ctx, 1, alice)
+	size := repository.TransactionSize(txs[0])
+
+	testCases := []struct {
+		desc      string
+		retention repository.Retention
+		want      repository.PruneStats
+		// kept transactions by address
+		kept map[types.Address]int
+	}{
+		{
+			desc:      "Disabled",
+			retention: repository.Retention{},
+			want:      repository.PruneStats{Transactions: 20, Bytes: 20 * size},
+			kept:      map[types.Address]int{alice: 10, bob: 10},
+		},
+		{
+			desc:      "Max age",
+			retention: repository.Retention{Default: repository.RetentionPolicy{MaxAge: 3 * time.Hour}},
+			want:      repository.PruneStats{EvictedByAge: 15, Transactions: 5, Bytes: 5 * size},
+			kept:      map[types.Address]int{alice: 3, bob: 2},
+		},
+		{
+			desc:      "Max transactions",
+			retention: repository.Retention{Default: repository.RetentionPolicy{MaxTransactions: 4}},
+			want:      repository.PruneStats{EvictedByCount: 12, Transactions: 8, Bytes: 8 * size},
+			kept:      map[types.Address]int{alice: 4, bob: 4},
+		},
+		{
+			desc: "Address overrides global limit",
+			retention: repository.NewRetention(
+				repository.RetentionPolicy{MaxAge: 2 * time.Hour, MaxTransactions: 1},
+				map[types.Address]repository.RetentionPolicy{alice: {MaxAge: 5 * time.Hour}},
+				0,
+			),
+			want: repository.PruneStats{EvictedByAge: 14, EvictedByCount: 4, Transactions: 2, Bytes: 2 * size},
+			kept: map[types.Address]int{alice: 1, bob: 1},
+		},
+		{
+			desc:      "Max bytes evicts the oldest of all addresses",
+			retention: repository.Retention{MaxBytes: 5 * size},
+			want:      repository.PruneStats{EvictedBySize: 15, Transactions: 5, Bytes: 5 * size},
+			kept:      map[types.Address]int{alice: 3, bob: 2},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			repo := newRepo()
+
+			stats, err := repo.Prune(ctx, tC.retention, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stats != tC.want {
+				t.Fatalf("stats are not equal, want %+v, got %+v", tC.want, stats)
+			}
+
+			for address, want := range tC.kept {
+				txs, _ := repo.GetTransactions(ctx, 1, address)
+				if len(txs) != want {
+					t.Fatalf("transactions count of %s is not equal, want %d, got %d", address, want, len(txs))
+				}
+
+				// the latest transactions are kept
+				if len(txs) > 0 && txs[len(txs)-1].Timestamp != latest[address].Unix() {
+					t.Fatalf("the latest transaction of %s is not equal, want timestamp %d, got %d",
+						address, latest[address].Unix(), txs[len(txs)-1].Timestamp)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package simnode
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/avelex/blockchain-parser/internal/keccak"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+const (
+	// timestamp of the first block
+	genesisTime = 1700000000
+	blockTime   = 12
+)
+
+type Transaction struct {
+	Hash string
+	From types.Address
+	// zero for contract creation
+	To types.Address
+	// reverted transactions have receipt status 0
+	Failed bool
+}
+
+type Block struct {
+	Number       int
+	Hash         string
+	ParentHash   string
+	Timestamp    int64
+	Transactions []Transaction
+}
+
+func genesisBlock(number int) Block {
+	return Block{
+		Number:     number,
+		Hash:       hash(fmt.Sprintf("block:%d", number)),
+		ParentHash: hash("genesis"),
+		Timestamp:  genesisTime,
+	}
+}
+
+// newBlock builds block on top of parent, fork makes hashes of replaced blocks differ after reorg
+func newBlock(parent Block, fork int, txs []Transaction) Block {
+	b := Block{
+		Number:       parent.Number + 1,
+		ParentHash:   parent.Hash,
+		Timestamp:    parent.Timestamp + blockTime,
+		Transactions: make([]Transaction, len(txs)),
+	}
+
+	b.Hash = hash(fmt.Sprintf("block:%d:%s:%d", b.Number, b.ParentHash, fork))
+
+	for i, tx := range txs {
+		if tx.Hash == "" {
+			tx.Hash = hash(fmt.Sprintf("tx:%s:%d", b.Hash, i))
+		}
+		b.Transactions[i] = tx
+	}
+
+	return b
+}
+
+func hash(s string) string {
+	sum := keccak.Sum256([]byte(s))
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+func toHex(i int64) string {
+	return fmt.Sprintf("0x%x", i)
+}
+
+func (b Block) json(fullTransactions bool) map[string]any {
+	txs := make([]any, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		if fullTransactions {
+			txs[i] = b.transactionJSON(i, tx)
+		} else {
+			txs[i] = tx.Hash
+		}
+	}
+
+	return map[string]any{
+		"number":       toHex(int64(b.Number)),
+		"hash":         b.Hash,
+		"parentHash":   b.ParentHash,
+		"timestamp":    toHex(b.Timestamp),
+		"transactions": txs,
+	}
+}
+
+func (b Block) transactionJSON(index int, tx Transaction) map[string]any {
+	return map[string]any{
+		"hash":             tx.Hash,
+		"blockHash":        b.Hash,
+		"blockNumber":      toHex(int64(b.Number)),
+		"transactionIndex": toHex(int64(index)),
+		"from":             addressJSON(tx.From),
+		"to":               addressJSON(tx.To),
+	}
+}
+
+func (b Block) receiptJSON(index int, tx Transaction) map[string]any {
+	status := "0x1"
+	if tx.Failed {
+		status = "0x0"
+	}
+
+	return map[string]any{
+		"transactionHash":  tx.Hash,
+		"blockHash":        b.Hash,
+		"blockNumber":      toHex(int64(b.Number)),
+		"transactionIndex": toHex(int64(index)),
+		"from":             addressJSON(tx.From),
+		"to":               addressJSON(tx.To),
+		"status":           status,
+	}
+}
+
+// addressJSON encodes address as node does, lowercase and null for zero address
+func addressJSON(a types.Address) any {
+	if a.IsZero() {
+		return nil
+	}
+	return a.Hex()
+}
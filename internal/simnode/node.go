@@ -0,0 +1,336 @@
+package simnode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSON-RPC error codes returned by the node
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternal       = -32603
+	codeLimitExceeded  = -32005
+)
+
+type txLocation struct {
+	block int
+	index int
+}
+
+// Node is a simulated Ethereum node serving JSON-RPC over HTTP from a programmable in-memory chain.
+// Use it with httptest.NewServer in tests or with http.ListenAndServe for local development.
+type Node struct {
+	mu      *sync.Mutex
+	chainID uint64
+	genesis int
+	blocks  []Block
+	txs     map[string]txLocation
+	fork    int
+
+	latency   time.Duration
+	failures  map[string]int
+	rateLimit int
+	window    time.Time
+	requests  int
+	calls     map[string]int
+}
+
+// New creates node of the chain with genesis block of the given number
+func New(chainID uint64, genesis int) *Node {
+	n := &Node{
+		mu:       &sync.Mutex{},
+		chainID:  chainID,
+		genesis:  genesis,
+		txs:      make(map[string]txLocation),
+		failures: make(map[string]int),
+		calls:    make(map[string]int),
+	}
+
+	n.blocks = append(n.blocks, genesisBlock(genesis))
+
+	return n
+}
+
+// Mine appends block with transactions to the chain
+func (n *Node) Mine(txs ...Transaction) Block {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	b := newBlock(n.blocks[len(n.blocks)-1], n.fork, txs)
+
+	n.blocks = append(n.blocks, b)
+	for i, tx := range b.Transactions {
+		n.txs[tx.Hash] = txLocation{block: b.Number, index: i}
+	}
+
+	return b
+}
+
+// MineEmpty appends count blocks without transactions
+func (n *Node) MineEmpty(count int) {
+	for i := 0; i < count; i++ {
+		n.Mine()
+	}
+}
+
+// Reorg drops depth blocks from the chain head, blocks mined afterwards get different hashes
+func (n *Node) Reorg(depth int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if depth >= len(n.blocks) {
+		panic(fmt.Sprintf("simnode: reorg depth %d exceeds chain length %d", depth, len(n.blocks)))
+	}
+
+	for _, b := range n.blocks[len(n.blocks)-depth:] {
+		for _, tx := range b.Transactions {
+			delete(n.txs, tx.Hash)
+		}
+	}
+
+	n.blocks = n.blocks[:len(n.blocks)-depth]
+	n.fork++
+}
+
+func (n *Node) Head() Block {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.blocks[len(n.blocks)-1]
+}
+
+// Block returns canonical block by number
+func (n *Node) Block(number int) (Block, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.block(number)
+}
+
+// SetLatency delays every response
+func (n *Node) SetLatency(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.latency = d
+}
+
+// FailNext makes the next count calls of method return internal error
+func (n *Node) FailNext(method string, count int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.failures[method] += count
+}
+
+// SetRateLimit limits number of requests per second, requests over the limit get 429 status, zero disables limit
+func (n *Node) SetRateLimit(perSecond int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.rateLimit = perSecond
+	n.window = time.Time{}
+	n.requests = 0
+}
+
+// Calls returns number of received calls of method, including failed ones
+func (n *Node) Calls(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.calls[method]
+}
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	latency := n.latency
+	limited := n.limited(time.Now())
+	n.calls[req.Method]++
+	n.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	resp := response{Version: "2.0", ID: req.ID}
+	status := http.StatusOK
+
+	if limited {
+		status = http.StatusTooManyRequests
+		resp.Error = &responseError{Code: codeLimitExceeded, Message: "rate limit exceeded"}
+	} else {
+		resp.Result, resp.Error = n.call(req)
+	}
+
+	if resp.Error != nil {
+		resp.Result = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// limited counts request in fixed one second window, must be called with mutex held
+func (n *Node) limited(now time.Time) bool {
+	if n.rateLimit <= 0 {
+		return false
+	}
+
+	if now.Sub(n.window) >= time.Second {
+		n.window = now
+		n.requests = 0
+	}
+
+	n.requests++
+
+	return n.requests > n.rateLimit
+}
+
+func (n *Node) call(req request) (any, *responseError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.failures[req.Method] > 0 {
+		n.failures[req.Method]--
+		return nil, &responseError{Code: codeInternal, Message: "internal error"}
+	}
+
+	switch req.Method {
+	case "eth_chainId":
+		return toHex(int64(n.chainID)), nil
+	case "net_version":
+		return strconv.FormatUint(n.chainID, 10), nil
+	case "eth_blockNumber":
+		return toHex(int64(n.blocks[len(n.blocks)-1].Number)), nil
+	case "eth_getBlockByNumber":
+		var tag string
+		if err := param(req.Params, 0, &tag); err != nil {
+			return nil, err
+		}
+
+		number, err := n.blockNumber(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		b, ok := n.block(number)
+		if !ok {
+			return nil, nil
+		}
+
+		return b.json(fullTransactions(req.Params)), nil
+	case "eth_getBlockByHash":
+		var blockHash string
+		if err := param(req.Params, 0, &blockHash); err != nil {
+			return nil, err
+		}
+
+		for _, b := range n.blocks {
+			if b.Hash == blockHash {
+				return b.json(fullTransactions(req.Params)), nil
+			}
+		}
+
+		return nil, nil
+	case "eth_getTransactionByHash", "eth_getTransactionReceipt":
+		var txHash string
+		if err := param(req.Params, 0, &txHash); err != nil {
+			return nil, err
+		}
+
+		loc, ok := n.txs[txHash]
+		if !ok {
+			return nil, nil
+		}
+
+		b, _ := n.block(loc.block)
+		if req.Method == "eth_getTransactionByHash" {
+			return b.transactionJSON(loc.index, b.Transactions[loc.index]), nil
+		}
+
+		return b.receiptJSON(loc.index, b.Transactions[loc.index]), nil
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method " + req.Method + " not found"}
+	}
+}
+
+// block must be called with mutex held
+func (n *Node) block(number int) (Block, bool) {
+	i := number - n.genesis
+	if i < 0 || i >= len(n.blocks) {
+		return Block{}, false
+	}
+	return n.blocks[i], true
+}
+
+// blockNumber resolves block tag, must be called with mutex held
+func (n *Node) blockNumber(tag string) (int, *responseError) {
+	switch tag {
+	case "latest", "safe", "finalized", "pending":
+		return n.blocks[len(n.blocks)-1].Number, nil
+	case "earliest":
+		return n.genesis, nil
+	}
+
+	number, err := strconv.ParseInt(strings.TrimPrefix(tag, "0x"), 16, 64)
+	if err != nil || !strings.HasPrefix(tag, "0x") {
+		return 0, &responseError{Code: codeInvalidParams, Message: "invalid block number " + tag}
+	}
+
+	return int(number), nil
+}
+
+func param(params []json.RawMessage, i int, v any) *responseError {
+	if i >= len(params) {
+		return &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("missing param %d", i)}
+	}
+
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid param %d", i)}
+	}
+
+	return nil
+}
+
+func fullTransactions(params []json.RawMessage) bool {
+	var full bool
+	if len(params) > 1 {
+		json.Unmarshal(params[1], &full)
+	}
+	return full
+}
@@ -0,0 +1,218 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+// Version of snapshot format
+const Version = 1
+
+var (
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+	ErrNotContinuous   = errors.New("snapshot is not continuous with processed blocks")
+)
+
+// Snapshot is repository state of chains, used to seed new instance instead of rescanning chains
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Chains    []Chain   `json:"chains"`
+}
+
+type Chain struct {
+	ChainID uint64 `json:"chain_id"`
+	// last processed block, parsing is resumed after it
+	Checkpoint    int                                   `json:"checkpoint"`
+	Processed     []repository.BlockRange               `json:"processed"`
+	Subscriptions []types.Address                       `json:"subscriptions"`
+	Transactions  map[types.Address][]types.Transaction `json:"transactions"`
+}
+
+// Take reads state of chains from repository.
+// Processed blocks are read before transactions, so snapshot of running instance may have transactions
+// of blocks after checkpoint, but never misses transactions of processed blocks.
+// Processed blocks may have gaps, e.g. failed blocks, they are restored as gaps to be filled by backfill.
+func Take(ctx context.Context, repo repository.Repository, chainIDs []uint64) (Snapshot, error) {
+	s := Snapshot{
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		Chains:    make([]Chain, 0, len(chainIDs)),
+	}
+
+	for _, chainID := range chainIDs {
+		ranges, err := repo.ProcessedRanges(ctx, chainID)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("failed to get processed blocks of chain %d: %w", chainID, err)
+		}
+
+		addresses, err := repo.Subscriptions(ctx, chainID)
+		if err != nil {
+			return Snapshot{}, fmt.Errorf("failed to get subscriptions of chain %d: %w", chainID, err)
+		}
+
+		c := Chain{
+			ChainID:       chainID,
+			Processed:     ranges,
+			Subscriptions: addresses,
+			Transactions:  make(map[types.Address][]types.Transaction),
+		}
+
+		if len(ranges) > 0 {
+			c.Checkpoint = ranges[len(ranges)-1].To
+		}
+
+		for _, address := range addresses {
+			// address without transactions
+			txs, err := repo.GetTransactions(ctx, chainID, address)
+			if err != nil || len(txs) == 0 {
+				continue
+			}
+			c.Transactions[address] = txs
+		}
+
+		s.Chains = append(s.Chains, c)
+	}
+
+	return s, nil
+}
+
+// Validate checks snapshot is complete and all its chains are parsed by target instance
+func (s Snapshot) Validate(chainIDs []uint64) error {
+	var errs []error
+
+	if s.Version != Version {
+		errs = append(errs, fmt.Errorf("version: unsupported %d, want %d", s.Version, Version))
+	}
+
+	seen := make(map[uint64]struct{}, len(s.Chains))
+
+	for i, c := range s.Chains {
+		prefix := fmt.Sprintf("chains[%d]", i)
+
+		if !slices.Contains(chainIDs, c.ChainID) {
+			errs = append(errs, fmt.Errorf("%s.chain_id: chain %d is not parsed, parsed chains %v", prefix, c.ChainID, chainIDs))
+		}
+
+		if _, ok := seen[c.ChainID]; ok {
+			errs = append(errs, fmt.Errorf("%s.chain_id: duplicate chain %d", prefix, c.ChainID))
+		}
+		seen[c.ChainID] = struct{}{}
+
+		for j, r := range c.Processed {
+			switch {
+			case r.From <= 0 || r.From > r.To:
+				errs = append(errs, fmt.Errorf("%s.processed[%d]: invalid range %d-%d", prefix, j, r.From, r.To))
+			case j > 0 && r.From <= c.Processed[j-1].To+1:
+				errs = append(errs, fmt.Errorf("%s.processed[%d]: range %d-%d must be sorted and separated by gap from %d-%d, adjacent ranges must be merged",
+					prefix, j, r.From, r.To, c.Processed[j-1].From, c.Processed[j-1].To))
+			}
+		}
+
+		switch {
+		case len(c.Processed) > 0 && c.Processed[len(c.Processed)-1].To != c.Checkpoint:
+			errs = append(errs, fmt.Errorf("%s.checkpoint: %d is not the last processed block %d", prefix, c.Checkpoint, c.Processed[len(c.Processed)-1].To))
+		case len(c.Processed) == 0 && c.Checkpoint != 0:
+			errs = append(errs, fmt.Errorf("%s.checkpoint: %d without processed blocks", prefix, c.Checkpoint))
+		}
+
+		for address, txs := range c.Transactions {
+			for _, tx := range txs {
+				if tx.ChainID != c.ChainID {
+					errs = append(errs, fmt.Errorf("%s.transactions: %s of %s has chain id %d", prefix, tx.Hash, address, tx.ChainID))
+					break
+				}
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w:\n%w", ErrInvalidSnapshot, err)
+	}
+
+	return nil
+}
+
+// Gaps returns blocks missing between the first and the last processed block of chain
+func (c Chain) Gaps() []repository.BlockRange {
+	if len(c.Processed) == 0 {
+		return nil
+	}
+	return repository.Gaps(c.Processed, c.Processed[0].From, c.Processed[len(c.Processed)-1].To)
+}
+
+// Restore loads validated snapshot into repository. Processed blocks of snapshot must not overlap
+// processed blocks of repository and must be adjacent to them, so restored chain has no gaps except Chain.Gaps.
+func Restore(ctx context.Context, repo repository.Repository, s Snapshot) error {
+	// all chains are checked before anything is written
+	for _, c := range s.Chains {
+		if err := checkContinuity(ctx, repo, c); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range s.Chains {
+		for _, address := range c.Subscriptions {
+			if err := repo.SaveSubscription(ctx, c.ChainID, address); err != nil {
+				return fmt.Errorf("failed to save subscription %s of chain %d: %w", address, c.ChainID, err)
+			}
+		}
+
+		// transactions are committed with processed blocks, interrupted restore leaves neither
+		batch := repository.NewBatch(c.ChainID)
+		for address, txs := range c.Transactions {
+			batch.SaveTransactions(address, txs)
+		}
+		for _, r := range c.Processed {
+			batch.MarkProcessedRange(r)
+		}
+
+		if err := repo.Commit(ctx, batch); err != nil {
+			return fmt.Errorf("failed to commit transactions and processed blocks of chain %d: %w", c.ChainID, err)
+		}
+	}
+
+	return nil
+}
+
+func checkContinuity(ctx context.Context, repo repository.Repository, c Chain) error {
+	if len(c.Processed) == 0 {
+		return nil
+	}
+
+	existing, err := repo.ProcessedRanges(ctx, c.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to get processed blocks of chain %d: %w", c.ChainID, err)
+	}
+
+	if len(existing) == 0 {
+		return nil
+	}
+
+	// gaps of snapshot are kept, so the whole span must be free
+	restored := repository.BlockRange{From: c.Processed[0].From, To: c.Processed[len(c.Processed)-1].To}
+	adjacent := false
+
+	for _, r := range existing {
+		if r.From <= restored.To && restored.From <= r.To {
+			return fmt.Errorf("%w: blocks %d-%d of chain %d are already processed", ErrNotContinuous, max(r.From, restored.From), min(r.To, restored.To), c.ChainID)
+		}
+
+		if r.To == restored.From-1 || r.From == restored.To+1 {
+			adjacent = true
+		}
+	}
+
+	if !adjacent {
+		return fmt.Errorf("%w: snapshot blocks %d-%d of chain %d leave gap to processed blocks %d-%d, start instance with start_block %d",
+			ErrNotContinuous, restored.From, restored.To, c.ChainID, existing[0].From, existing[len(existing)-1].To, restored.To+1)
+	}
+
+	return nil
+}
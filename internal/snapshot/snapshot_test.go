@@ -0,0 +1,170 @@
+package snapshot_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/repository"
+	"github.com/avelex/blockchain-parser/internal/repository/memory"
+	"github.com/avelex/blockchain-parser/internal/snapshot"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+var (
+	alice = types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	bob   = types.MustParseAddress("0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359")
+)
+
+// source returns repository of chain 1 with blocks 100-110 processed
+func source(t *testing.T) *memory.Repository {
+	t.Helper()
+
+	ctx := context.Background()
+	repo := memory.New()
+
+	repo.SaveSubscription(ctx, 1, alice)
+	repo.SaveSubscription(ctx, 1, bob)
+	repo.SaveTransactions(ctx, 1, alice, []types.Transaction{types.NewTransaction(1, "0x01", alice, bob, 1736000000)})
+	repo.MarkProcessedRange(ctx, 1, repository.BlockRange{From: 100, To: 110})
+
+	return repo
+}
+
+func Test_Restore(t *testing.T) {
+	testCases := []struct {
+		desc string
+		// processed by source besides 100-110
+		processed []repository.BlockRange
+		existing  []repository.BlockRange
+		want      []repository.BlockRange
+		err       error
+	}{
+		{
+			desc: "Empty Repository",
+			want: []repository.BlockRange{{From: 100, To: 110}},
+		},
+		{
+			desc:     "Adjacent To Processed Blocks",
+			existing: []repository.BlockRange{{From: 111, To: 120}},
+			want:     []repository.BlockRange{{From: 100, To: 120}},
+		},
+		{
+			desc:      "Gap In Snapshot",
+			processed: []repository.BlockRange{{From: 115, To: 118}},
+			existing:  []repository.BlockRange{{From: 119, To: 125}},
+			want:      []repository.BlockRange{{From: 100, To: 110}, {From: 115, To: 125}},
+		},
+		{
+			desc:      "Processed Blocks In Gap Of Snapshot",
+			processed: []repository.BlockRange{{From: 115, To: 118}},
+			existing:  []repository.BlockRange{{From: 112, To: 113}},
+			err:       snapshot.ErrNotContinuous,
+		},
+		{
+			desc:     "Overlaps Processed Blocks",
+			existing: []repository.BlockRange{{From: 105, To: 120}},
+			err:      snapshot.ErrNotContinuous,
+		},
+		{
+			desc:     "Gap To Processed Blocks",
+			existing: []repository.BlockRange{{From: 130, To: 140}},
+			err:      snapshot.ErrNotContinuous,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+
+			src := source(t)
+			for _, r := range tC.processed {
+				src.MarkProcessedRange(ctx, 1, r)
+			}
+
+			s, err := snapshot.Take(ctx, src, []uint64{1})
+			if err != nil {
+				t.Fatalf("failed to take snapshot: %v", err)
+			}
+
+			if err := s.Validate([]uint64{1}); err != nil {
+				t.Fatalf("failed to validate snapshot: %v", err)
+			}
+
+			target := memory.New()
+			for _, r := range tC.existing {
+				target.MarkProcessedRange(ctx, 1, r)
+			}
+
+			err = snapshot.Restore(ctx, target, s)
+			if !errors.Is(err, tC.err) {
+				t.Fatalf("error is not equal, want %v, got %v", tC.err, err)
+			}
+
+			restored, err := snapshot.Take(ctx, target, []uint64{1})
+			if err != nil {
+				t.Fatalf("failed to take snapshot of target: %v", err)
+			}
+
+			if tC.err != nil {
+				if len(restored.Chains[0].Subscriptions) != 0 {
+					t.Fatalf("rejected snapshot is partially restored")
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(s.Chains[0].Transactions, restored.Chains[0].Transactions) {
+				t.Fatalf("transactions are not equal, want %v, got %v", s.Chains[0].Transactions, restored.Chains[0].Transactions)
+			}
+
+			if !reflect.DeepEqual(s.Chains[0].Subscriptions, restored.Chains[0].Subscriptions) {
+				t.Fatalf("subscriptions are not equal, want %v, got %v", s.Chains[0].Subscriptions, restored.Chains[0].Subscriptions)
+			}
+
+			if got := restored.Chains[0].Processed; !reflect.DeepEqual(tC.want, got) {
+				t.Fatalf("processed blocks are not equal, want %v, got %v", tC.want, got)
+			}
+		})
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		modify func(s *snapshot.Snapshot)
+	}{
+		{
+			desc:   "Chain Is Not Parsed",
+			modify: func(s *snapshot.Snapshot) { s.Chains[0].ChainID = 8453 },
+		},
+		{
+			desc: "Overlapping Processed Blocks",
+			modify: func(s *snapshot.Snapshot) {
+				s.Chains[0].Processed = append(s.Chains[0].Processed, repository.BlockRange{From: 105, To: 130})
+				s.Chains[0].Checkpoint = 130
+			},
+		},
+		{
+			desc:   "Checkpoint Is Not The Last Processed Block",
+			modify: func(s *snapshot.Snapshot) { s.Chains[0].Checkpoint = 200 },
+		},
+		{
+			desc:   "Unsupported Version",
+			modify: func(s *snapshot.Snapshot) { s.Version = 2 },
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, err := snapshot.Take(context.Background(), source(t), []uint64{1})
+			if err != nil {
+				t.Fatalf("failed to take snapshot: %v", err)
+			}
+
+			tC.modify(&s)
+
+			if err := s.Validate([]uint64{1}); !errors.Is(err, snapshot.ErrInvalidSnapshot) {
+				t.Fatalf("error is not equal, want %v, got %v", snapshot.ErrInvalidSnapshot, err)
+			}
+		})
+	}
+}
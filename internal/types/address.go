@@ -0,0 +1,133 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/avelex/blockchain-parser/internal/keccak"
+)
+
+const addressLength = 20
+
+var (
+	ErrInvalidAddress  = errors.New("invalid address")
+	ErrInvalidChecksum = errors.New("invalid address checksum")
+)
+
+// Address is 20 bytes Ethereum address, zero value means no address
+// e.g. recipient of contract creation transaction.
+// Addresses are created by ParseAddress only, so EIP-55 checksum is computed once.
+type Address struct {
+	// canonical lowercase 0x-prefixed hex
+	hex      string
+	checksum string
+}
+
+// ParseAddress validates 0x-prefixed hex address, mixed-case input must have valid EIP-55 checksum
+func ParseAddress(s string) (Address, error) {
+	hexPart, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		hexPart, ok = strings.CutPrefix(s, "0X")
+	}
+
+	if !ok || len(hexPart) != 2*addressLength {
+		return Address{}, ErrInvalidAddress
+	}
+
+	if _, err := hex.DecodeString(hexPart); err != nil {
+		return Address{}, ErrInvalidAddress
+	}
+
+	lower := strings.ToLower(hexPart)
+	address := Address{hex: "0x" + lower, checksum: checksum(lower)}
+
+	// all lower or all upper case addresses carry no checksum
+	if hexPart == lower || hexPart == strings.ToUpper(hexPart) {
+		return address, nil
+	}
+
+	if "0x"+hexPart != address.checksum {
+		return Address{}, ErrInvalidChecksum
+	}
+
+	return address, nil
+}
+
+// MustParseAddress is ParseAddress which panics on invalid input
+func MustParseAddress(s string) Address {
+	address, err := ParseAddress(s)
+	if err != nil {
+		panic(fmt.Sprintf("types: %s: %v", s, err))
+	}
+	return address
+}
+
+// checksum returns EIP-55 mixed-case encoding of lowercase hex
+func checksum(lower string) string {
+	hash := keccak.Sum256([]byte(lower))
+
+	out := []byte(lower)
+	for i, c := range out {
+		if c < 'a' || c > 'f' {
+			continue
+		}
+
+		// i-th nibble of hash decides case of i-th character
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+
+		if nibble&0x0f >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(out)
+}
+
+// Checksum returns EIP-55 mixed-case encoding of address, empty for zero address
+func (a Address) Checksum() string {
+	return a.checksum
+}
+
+// Hex returns canonical lowercase encoding of address, empty for zero address
+func (a Address) Hex() string {
+	return a.hex
+}
+
+func (a Address) IsZero() bool {
+	return a.hex == ""
+}
+
+// Compare orders addresses by canonical encoding, e.g. for slices.SortFunc
+func (a Address) Compare(b Address) int {
+	return strings.Compare(a.hex, b.hex)
+}
+
+func (a Address) String() string {
+	return a.checksum
+}
+
+// MarshalText encodes address with checksum, it's also used for JSON values and map keys
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.checksum), nil
+}
+
+// UnmarshalText accepts empty text as zero address
+func (a *Address) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*a = Address{}
+		return nil
+	}
+
+	address, err := ParseAddress(string(text))
+	if err != nil {
+		return fmt.Errorf("failed to parse address %q: %w", text, err)
+	}
+
+	*a = address
+	return nil
+}
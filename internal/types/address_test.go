@@ -0,0 +1,128 @@
+package types_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+func Test_ParseAddress(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		input    string
+		want     string
+		checksum string
+		err      error
+	}{
+		{
+			desc:     "Valid Checksum",
+			input:    "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+			want:     "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			checksum: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		},
+		{
+			desc:     "Lowercase",
+			input:    "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359",
+			want:     "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359",
+			checksum: "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		},
+		{
+			desc:     "Uppercase",
+			input:    "0xDBF03B407C01E7CD3CBEA99509D93F8DDDC8C6FB",
+			want:     "0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb",
+			checksum: "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		},
+		{
+			desc:     "Valid Checksum With Digits Only Prefix",
+			input:    "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+			want:     "0xd1220a0cf47c7b9be7a2e6ba89f429762e7b9adb",
+			checksum: "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+		},
+		{
+			desc:  "Invalid Checksum",
+			input: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD",
+			err:   types.ErrInvalidChecksum,
+		},
+		{
+			desc:  "Without Prefix",
+			input: "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+			err:   types.ErrInvalidAddress,
+		},
+		{
+			desc:  "Too Short",
+			input: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea",
+			err:   types.ErrInvalidAddress,
+		},
+		{
+			desc:  "Not Hex",
+			input: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beazz",
+			err:   types.ErrInvalidAddress,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			got, err := types.ParseAddress(tC.input)
+			if !errors.Is(err, tC.err) {
+				t.Fatalf("error is not equal, want %v, got %v", tC.err, err)
+			}
+
+			if got.Hex() != tC.want {
+				t.Fatalf("address is not equal, want %s, got %s", tC.want, got.Hex())
+			}
+
+			if got.Checksum() != tC.checksum {
+				t.Fatalf("checksum is not equal, want %s, got %s", tC.checksum, got.Checksum())
+			}
+		})
+	}
+}
+
+func Test_AddressJSON(t *testing.T) {
+	address := types.MustParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+
+	testCases := []struct {
+		desc  string
+		value any
+		want  string
+	}{
+		{
+			desc:  "Checksum",
+			value: address,
+			want:  `"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"`,
+		},
+		{
+			desc:  "Zero",
+			value: types.Address{},
+			want:  `""`,
+		},
+		{
+			desc:  "Map Key",
+			value: map[types.Address]int{address: 1},
+			want:  `{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed":1}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			data, err := json.Marshal(tC.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != tC.want {
+				t.Fatalf("json is not equal, want %s, got %s", tC.want, data)
+			}
+		})
+	}
+
+	// lowercase addresses are accepted, e.g. written before checksum encoding
+	var got map[types.Address]types.Address
+	if err := json.Unmarshal([]byte(`{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed":"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got[address] != address {
+		t.Fatalf("address is not equal, want %s, got %v", address, got)
+	}
+}
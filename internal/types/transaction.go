@@ -1,14 +1,16 @@
 package types
 
 type Transaction struct {
-	Hash      string `json:"hash"`
-	From      string `json:"from"`
-	To        string `json:"to"`
-	Timestamp int64  `json:"timestamp"`
+	ChainID   uint64  `json:"chain_id"`
+	Hash      string  `json:"hash"`
+	From      Address `json:"from"`
+	To        Address `json:"to"`
+	Timestamp int64   `json:"timestamp"`
 }
 
-func NewTransaction(hash, from, to string, timestamp int64) Transaction {
+func NewTransaction(chainID uint64, hash string, from, to Address, timestamp int64) Transaction {
 	return Transaction{
+		ChainID:   chainID,
 		Hash:      hash,
 		From:      from,
 		To:        to,
@@ -0,0 +1,523 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/types"
+)
+
+const (
+	SignatureHeader = "X-Signature-256"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	EventHeader     = "X-Webhook-Event"
+)
+
+const (
+	maxBackoff = time.Hour
+
+	// number of finished deliveries kept per subscription
+	historyLimit = 100
+	pollInterval = time.Second
+	// number of subscriptions delivered concurrently
+	deliveryWorkers = 16
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusFailed    Status = "failed"
+)
+
+type Subscription struct {
+	ID      string        `json:"id"`
+	Address types.Address `json:"address"`
+	URL     string        `json:"url"`
+	// ID of API key which created subscription, empty if auth is disabled
+	KeyID string `json:"key_id,omitempty"`
+}
+
+type Attempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type Delivery struct {
+	ID             string       `json:"id"`
+	SubscriptionID string       `json:"subscription_id"`
+	Event          events.Event `json:"event"`
+	Status         Status       `json:"status"`
+	Attempts       []Attempt    `json:"attempts"`
+	NextAttempt    time.Time    `json:"next_attempt"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// Dispatcher delivers events of subscribed addresses to callback URLs.
+// Subscriptions and undelivered events are persisted to the outbox file and survive restarts.
+type Dispatcher struct {
+	mu            *sync.Mutex
+	subscriptions map[string]Subscription
+	// subscription IDs in creation order, so compacted outbox keeps the order
+	order      []string
+	byAddress  map[types.Address][]string
+	deliveries []*Delivery
+	// nil if outbox path is not set
+	outbox *outbox
+	// subscriptions being delivered, so their deliveries are sent one by one in order
+	inFlight map[string]struct{}
+
+	wake    chan struct{}
+	workers chan struct{}
+
+	conf   config.WebhookConfig
+	client *http.Client
+}
+
+func New(conf config.WebhookConfig, broker *events.Broker) (*Dispatcher, error) {
+	d := &Dispatcher{
+		mu:            &sync.Mutex{},
+		subscriptions: make(map[string]Subscription),
+		byAddress:     make(map[types.Address][]string),
+		inFlight:      make(map[string]struct{}),
+		wake:          make(chan struct{}, 1),
+		workers:       make(chan struct{}, deliveryWorkers),
+		conf:          conf,
+		client:        &http.Client{Timeout: conf.Timeout},
+	}
+
+	if conf.Secret == "" {
+		slog.Warn("webhook.secret is empty, deliveries are signed with empty key")
+	}
+
+	if conf.OutboxPath != "" {
+		o, subscriptions, deliveries, err := openOutbox(conf.OutboxPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load outbox: %w", err)
+		}
+
+		d.outbox = o
+		for _, sub := range subscriptions {
+			d.addSubscription(sub)
+		}
+		d.deliveries = deliveries
+		d.trimHistory()
+	}
+
+	// events are enqueued by publisher, so they are persisted even if delivery falls behind
+	broker.Handle(d.enqueue)
+
+	return d, nil
+}
+
+// Close closes outbox file, deliveries must be stopped before
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.outbox == nil {
+		return nil
+	}
+
+	return d.outbox.Close()
+}
+
+func (d *Dispatcher) addSubscription(sub Subscription) {
+	d.subscriptions[sub.ID] = sub
+	d.order = append(d.order, sub.ID)
+	d.byAddress[sub.Address] = append(d.byAddress[sub.Address], sub.ID)
+}
+
+// Subscribe registers callback URL for address on behalf of key, returns existing subscription of the key for the same pair
+func (d *Dispatcher) Subscribe(address types.Address, url, keyID string) (Subscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, id := range d.byAddress[address] {
+		if sub := d.subscriptions[id]; sub.URL == url && sub.KeyID == keyID {
+			return sub, nil
+		}
+	}
+
+	sub := Subscription{
+		ID:      randomID(),
+		Address: address,
+		URL:     url,
+		KeyID:   keyID,
+	}
+
+	if err := d.save(record{Subscription: &sub}); err != nil {
+		return Subscription{}, err
+	}
+
+	d.addSubscription(sub)
+
+	return sub, nil
+}
+
+func (d *Dispatcher) Subscription(id string) (Subscription, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sub, ok := d.subscriptions[id]
+	return sub, ok
+}
+
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	subs := make([]Subscription, 0, len(d.order))
+	for _, id := range d.order {
+		subs = append(subs, d.subscriptions[id])
+	}
+
+	return subs
+}
+
+// Deliveries returns pending and recent deliveries of subscription, false if subscription not found
+func (d *Dispatcher) Deliveries(subscriptionID string) ([]Delivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.subscriptions[subscriptionID]; !ok {
+		return nil, false
+	}
+
+	deliveries := []Delivery{}
+	for _, delivery := range d.deliveries {
+		if delivery.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+
+	return deliveries, true
+}
+
+// Start delivers enqueued events until context is done
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.deliverLoop(ctx)
+	return nil
+}
+
+func (d *Dispatcher) enqueue(e events.Event) {
+	if e.Address.IsZero() {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ids := d.byAddress[e.Address]
+	if len(ids) == 0 {
+		return
+	}
+
+	now := time.Now()
+
+	for _, id := range ids {
+		delivery := &Delivery{
+			ID:             randomID(),
+			SubscriptionID: id,
+			Event:          e,
+			Status:         StatusPending,
+			Attempts:       []Attempt{},
+			NextAttempt:    now,
+			CreatedAt:      now,
+		}
+
+		// delivery is still attempted, it's lost only if process restarts before it's delivered
+		if err := d.save(record{Delivery: delivery}); err != nil {
+			slog.Error("failed to persist webhook delivery", "delivery", delivery.ID, "error", err)
+		}
+
+		d.deliveries = append(d.deliveries, delivery)
+	}
+
+	d.notify()
+}
+
+// notify wakes delivery loop
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) deliverLoop(ctx context.Context) {
+	timer := time.NewTimer(pollInterval)
+	defer timer.Stop()
+
+	// attempts are recorded to outbox, so it's closed after workers are done
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+
+	for {
+		d.deliverDue(ctx, wg)
+
+		timer.Reset(d.nextWait(time.Now()))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// deliverDue starts worker per subscription with due deliveries, at most deliveryWorkers of them send at once
+func (d *Dispatcher) deliverDue(ctx context.Context, wg *sync.WaitGroup) {
+	for _, deliveries := range d.due(time.Now()) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer d.release(deliveries[0].SubscriptionID)
+
+			select {
+			case d.workers <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-d.workers }()
+
+			for _, delivery := range deliveries {
+				if ctx.Err() != nil {
+					return
+				}
+
+				attempt := d.send(ctx, delivery)
+				d.record(delivery.ID, attempt)
+			}
+		}()
+	}
+}
+
+// release marks subscription delivered and wakes delivery loop for deliveries enqueued meanwhile
+func (d *Dispatcher) release(subscriptionID string) {
+	d.mu.Lock()
+	delete(d.inFlight, subscriptionID)
+	d.mu.Unlock()
+
+	d.notify()
+}
+
+// nextWait returns duration until the earliest pending attempt, at most poll interval
+func (d *Dispatcher) nextWait(now time.Time) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	wait := pollInterval
+	for _, delivery := range d.deliveries {
+		// subscription being delivered wakes the loop when it's done
+		if _, ok := d.inFlight[delivery.SubscriptionID]; ok {
+			continue
+		}
+
+		if delivery.Status == StatusPending {
+			wait = min(wait, max(delivery.NextAttempt.Sub(now), 0))
+		}
+	}
+
+	return wait
+}
+
+// due returns copies of pending deliveries ready for next attempt grouped by subscription in enqueue order,
+// subscriptions being delivered are skipped and returned ones are marked in flight
+func (d *Dispatcher) due(now time.Time) [][]Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	index := make(map[string]int)
+	var due [][]Delivery
+
+	for _, delivery := range d.deliveries {
+		if delivery.Status != StatusPending || delivery.NextAttempt.After(now) {
+			continue
+		}
+
+		if _, ok := d.inFlight[delivery.SubscriptionID]; ok {
+			continue
+		}
+
+		i, ok := index[delivery.SubscriptionID]
+		if !ok {
+			i = len(due)
+			index[delivery.SubscriptionID] = i
+			due = append(due, nil)
+		}
+		due[i] = append(due[i], *delivery)
+	}
+
+	for id := range index {
+		d.inFlight[id] = struct{}{}
+	}
+
+	return due
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery) Attempt {
+	attempt := Attempt{Time: time.Now()}
+
+	d.mu.Lock()
+	sub, ok := d.subscriptions[delivery.SubscriptionID]
+	d.mu.Unlock()
+
+	if !ok {
+		attempt.Error = "subscription not found"
+		return attempt
+	}
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign([]byte(d.conf.Secret), body))
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(EventHeader, string(delivery.Event.Type))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, resp.Body)
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("unexpected status %s", resp.Status)
+	}
+
+	return attempt
+}
+
+func (d *Dispatcher) record(deliveryID string, attempt Attempt) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, delivery := range d.deliveries {
+		if delivery.ID != deliveryID {
+			continue
+		}
+
+		delivery.Attempts = append(delivery.Attempts, attempt)
+
+		switch {
+		case attempt.Error == "":
+			delivery.Status = StatusDelivered
+		case len(delivery.Attempts) >= d.conf.MaxAttempts:
+			delivery.Status = StatusFailed
+			slog.Warn("Webhook delivery failed", "delivery", delivery.ID, "subscription", delivery.SubscriptionID, "attempts", len(delivery.Attempts))
+		default:
+			delivery.NextAttempt = attempt.Time.Add(d.backoff(len(delivery.Attempts)))
+		}
+
+		if err := d.save(record{Delivery: delivery}); err != nil {
+			slog.Error("failed to persist webhook delivery", "delivery", delivery.ID, "error", err)
+		}
+
+		break
+	}
+
+	d.trimHistory()
+
+	if err := d.compact(); err != nil {
+		slog.Error("failed to compact webhook outbox", "error", err)
+	}
+}
+
+// backoff returns exponential delay before next attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.conf.RetryBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxBackoff)
+}
+
+// trimHistory drops the oldest finished deliveries above history limit per subscription
+func (d *Dispatcher) trimHistory() {
+	finished := make(map[string]int)
+	for _, delivery := range d.deliveries {
+		if delivery.Status != StatusPending {
+			finished[delivery.SubscriptionID]++
+		}
+	}
+
+	kept := d.deliveries[:0]
+	for _, delivery := range d.deliveries {
+		if delivery.Status != StatusPending && finished[delivery.SubscriptionID] > historyLimit {
+			finished[delivery.SubscriptionID]--
+			continue
+		}
+		kept = append(kept, delivery)
+	}
+
+	d.deliveries = kept
+}
+
+// save appends record to the outbox, must be called with mutex held
+func (d *Dispatcher) save(r record) error {
+	if d.outbox == nil {
+		return nil
+	}
+	return d.outbox.append(r)
+}
+
+// compact drops trimmed deliveries and previous states from the outbox, must be called with mutex held
+func (d *Dispatcher) compact() error {
+	if d.outbox == nil {
+		return nil
+	}
+
+	subscriptions := make([]Subscription, 0, len(d.order))
+	for _, id := range d.order {
+		subscriptions = append(subscriptions, d.subscriptions[id])
+	}
+
+	return d.outbox.compact(subscriptions, d.deliveries)
+}
+
+// Sign returns value of signature header, hex encoded HMAC-SHA256 of body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomID() string {
+	buff := make([]byte, 8)
+	if _, err := rand.Read(buff); err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buff)
+}
@@ -0,0 +1,291 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/config"
+	"github.com/avelex/blockchain-parser/internal/events"
+	"github.com/avelex/blockchain-parser/internal/types"
+	"github.com/avelex/blockchain-parser/internal/webhook"
+)
+
+var (
+	testAddress     = types.MustParseAddress("0xe93685f3bba03016f02bd1828badd6195988d950")
+	testCounterpart = types.MustParseAddress("0x22a7a914cf352f7361c199188a23da94fe71b277")
+)
+
+func Test_Delivery(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		failures     int32
+		maxAttempts  int
+		wantStatus   webhook.Status
+		wantAttempts int
+	}{
+		{
+			desc:         "Delivered First Attempt",
+			failures:     0,
+			maxAttempts:  3,
+			wantStatus:   webhook.StatusDelivered,
+			wantAttempts: 1,
+		},
+		{
+			desc:         "Delivered After Retries",
+			failures:     2,
+			maxAttempts:  3,
+			wantStatus:   webhook.StatusDelivered,
+			wantAttempts: 3,
+		},
+		{
+			desc:         "Failed After Max Attempts",
+			failures:     5,
+			maxAttempts:  2,
+			wantStatus:   webhook.StatusFailed,
+			wantAttempts: 2,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			secret := "secret"
+
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if got, want := r.Header.Get(webhook.SignatureHeader), webhook.Sign([]byte(secret), body); got != want {
+					t.Errorf("signature is not equal, want %s, got %s", want, got)
+				}
+
+				if calls.Add(1) <= tC.failures {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+			}))
+			defer server.Close()
+
+			conf := config.Default().Webhook
+			conf.Secret = secret
+			conf.MaxAttempts = tC.maxAttempts
+			conf.RetryBackoff = 10 * time.Millisecond
+
+			broker := events.NewBroker()
+			dispatcher, err := webhook.New(conf, broker)
+			if err != nil {
+				t.Fatalf("failed to create dispatcher: %v", err)
+			}
+
+			sub, err := dispatcher.Subscribe(testAddress, server.URL, "")
+			if err != nil {
+				t.Fatalf("failed to subscribe: %v", err)
+			}
+
+			startDispatcher(t, dispatcher)
+			publishTransaction(broker)
+
+			delivery := waitDelivery(t, dispatcher, sub.ID, tC.wantStatus)
+
+			if len(delivery.Attempts) != tC.wantAttempts {
+				t.Fatalf("attempts count is not equal, want %d, got %d", tC.wantAttempts, len(delivery.Attempts))
+			}
+		})
+	}
+}
+
+func Test_OutboxSurvivesRestart(t *testing.T) {
+	outbox := filepath.Join(t.TempDir(), "webhooks.json")
+	conf := config.Default().Webhook
+	conf.OutboxPath = outbox
+	conf.RetryBackoff = 10 * time.Millisecond
+
+	var available atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	broker := events.NewBroker()
+	first, err := webhook.New(conf, broker)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+
+	sub, err := first.Subscribe(testAddress, server.URL, "")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	stop := startDispatcher(t, first)
+
+	publishTransaction(broker)
+	waitAttempts(t, first, sub.ID, 1)
+
+	stop()
+	if err := first.Close(); err != nil {
+		t.Fatalf("failed to close dispatcher: %v", err)
+	}
+
+	available.Store(true)
+
+	second, err := webhook.New(conf, events.NewBroker())
+	if err != nil {
+		t.Fatalf("failed to restore dispatcher: %v", err)
+	}
+
+	if subs := second.Subscriptions(); len(subs) != 1 || subs[0] != sub {
+		t.Fatalf("subscriptions are not restored, got %v", subs)
+	}
+
+	startDispatcher(t, second)
+	waitDelivery(t, second, sub.ID, webhook.StatusDelivered)
+}
+
+func Test_EventsEnqueuedBeforeStart(t *testing.T) {
+	var delivered atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered.Add(1)
+	}))
+	defer server.Close()
+
+	conf := config.Default().Webhook
+	conf.OutboxPath = filepath.Join(t.TempDir(), "webhooks.json")
+
+	broker := events.NewBroker()
+	dispatcher, err := webhook.New(conf, broker)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	defer dispatcher.Close()
+
+	sub, err := dispatcher.Subscribe(testAddress, server.URL, "")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	// more events than any subscriber buffer, none is dropped while deliveries are not started
+	const published = 2000
+	for i := 0; i < published; i++ {
+		publishTransaction(broker)
+	}
+
+	deliveries, _ := dispatcher.Deliveries(sub.ID)
+	if len(deliveries) != published {
+		t.Fatalf("deliveries count is not equal, want %d, got %d", published, len(deliveries))
+	}
+
+	startDispatcher(t, dispatcher)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for delivered.Load() < published {
+		if time.Now().After(deadline) {
+			t.Fatalf("delivered count is not equal, want %d, got %d", published, delivered.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_SlowSubscription(t *testing.T) {
+	hang := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer slow.Close()
+	defer close(hang)
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	broker := events.NewBroker()
+	dispatcher, err := webhook.New(config.Default().Webhook, broker)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+
+	if _, err := dispatcher.Subscribe(testAddress, slow.URL, ""); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	sub, err := dispatcher.Subscribe(testAddress, fast.URL, "")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	stop := startDispatcher(t, dispatcher)
+	defer stop()
+
+	publishTransaction(broker)
+
+	// delivery to the slow subscription hangs until the test ends
+	waitDelivery(t, dispatcher, sub.ID, webhook.StatusDelivered)
+}
+
+// startDispatcher runs dispatcher until returned stop is called or the test ends, stop waits for Start to return
+func startDispatcher(t *testing.T, dispatcher *webhook.Dispatcher) func() {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		dispatcher.Start(ctx)
+	}()
+
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+
+	return stop
+}
+
+func publishTransaction(broker *events.Broker) {
+	tx := types.NewTransaction(1, "0x01", testAddress, testCounterpart, 1)
+
+	broker.Publish(events.Event{
+		Type:        events.TypeTransaction,
+		ChainID:     1,
+		Address:     testAddress,
+		Block:       1,
+		Transaction: &tx,
+	})
+}
+
+func waitDelivery(t *testing.T, dispatcher *webhook.Dispatcher, subscriptionID string, status webhook.Status) webhook.Delivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, _ := dispatcher.Deliveries(subscriptionID)
+		if len(deliveries) == 1 && deliveries[0].Status == status {
+			return deliveries[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("delivery didn't reach status %s", status)
+	return webhook.Delivery{}
+}
+
+func waitAttempts(t *testing.T, dispatcher *webhook.Dispatcher, subscriptionID string, attempts int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, _ := dispatcher.Deliveries(subscriptionID)
+		if len(deliveries) == 1 && len(deliveries[0].Attempts) >= attempts {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("delivery didn't reach %d attempts", attempts)
+}
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/avelex/blockchain-parser/internal/fsutil"
+)
+
+const (
+	// journal is compacted when it has compactRatio times more lines than subscriptions and deliveries
+	compactRatio = 4
+	// small journal is not compacted
+	compactMinLines = 1024
+)
+
+// record is a line of the outbox journal, subscription or delivery replacing the previous line with the same ID
+type record struct {
+	Subscription *Subscription `json:"subscription,omitempty"`
+	Delivery     *Delivery     `json:"delivery,omitempty"`
+}
+
+// outbox is append-only JSON lines journal of subscriptions and deliveries, so enqueued event costs one line
+type outbox struct {
+	f     *os.File
+	path  string
+	lines int
+}
+
+// openOutbox replays journal at path, the file is created if missing
+func openOutbox(path string) (*outbox, []Subscription, []*Delivery, error) {
+	j, err := replayOutbox(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// next lines would be appended to incomplete line otherwise
+	if err := f.Truncate(j.size); err != nil {
+		f.Close()
+		return nil, nil, nil, err
+	}
+
+	return &outbox{f: f, path: path, lines: j.lines}, j.subscriptions, j.deliveries, nil
+}
+
+// journal is replayed outbox, subscriptions and deliveries are in the order of their first line
+type journal struct {
+	subscriptions []Subscription
+	deliveries    []*Delivery
+	lines         int
+	// size of valid lines
+	size int64
+}
+
+func replayOutbox(path string) (journal, error) {
+	var j journal
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return j, err
+	}
+	defer f.Close()
+
+	subIndex := make(map[string]int)
+	deliveryIndex := make(map[string]int)
+
+	addSubscription := func(sub Subscription) {
+		if i, ok := subIndex[sub.ID]; ok {
+			j.subscriptions[i] = sub
+			return
+		}
+		subIndex[sub.ID] = len(j.subscriptions)
+		j.subscriptions = append(j.subscriptions, sub)
+	}
+
+	addDelivery := func(delivery *Delivery) {
+		if i, ok := deliveryIndex[delivery.ID]; ok {
+			j.deliveries[i] = delivery
+			return
+		}
+		deliveryIndex[delivery.ID] = len(j.deliveries)
+		j.deliveries = append(j.deliveries, delivery)
+	}
+
+	reader := bufio.NewReader(f)
+
+	for {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(data) > 0 {
+				// write interrupted by crash, the line is dropped
+				slog.Warn("Ignored incomplete last line of webhook outbox", "path", path)
+			}
+			return j, nil
+		}
+		if err != nil {
+			return j, err
+		}
+
+		var r record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return j, fmt.Errorf("failed to parse line %d: %w", j.lines+1, err)
+		}
+
+		j.lines++
+		j.size += int64(len(data))
+
+		if r.Subscription != nil {
+			addSubscription(*r.Subscription)
+		}
+		if r.Delivery != nil {
+			addDelivery(r.Delivery)
+		}
+	}
+}
+
+func (o *outbox) append(r record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox record: %w", err)
+	}
+
+	if err := fsutil.AppendLine(o.f, data); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	o.lines++
+
+	return nil
+}
+
+// compact replaces journal with a line per subscription and delivery if it has grown enough
+func (o *outbox) compact(subscriptions []Subscription, deliveries []*Delivery) error {
+	records := len(subscriptions) + len(deliveries)
+	if o.lines < compactMinLines || o.lines < records*compactRatio {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, sub := range subscriptions {
+		if err := encoder.Encode(record{Subscription: &sub}); err != nil {
+			return fmt.Errorf("failed to marshal outbox record: %w", err)
+		}
+	}
+	for _, delivery := range deliveries {
+		if err := encoder.Encode(record{Delivery: delivery}); err != nil {
+			return fmt.Errorf("failed to marshal outbox record: %w", err)
+		}
+	}
+
+	if err := fsutil.WriteFileAtomic(o.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to compact outbox: %w", err)
+	}
+
+	// file was replaced, old descriptor points to the removed one
+	f, err := os.OpenFile(o.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
+
+	o.f.Close()
+	o.f = f
+	o.lines = records
+
+	return nil
+}
+
+func (o *outbox) Close() error {
+	return o.f.Close()
+}
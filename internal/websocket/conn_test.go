@@ -0,0 +1,252 @@
+package websocket_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avelex/blockchain-parser/internal/websocket"
+)
+
+// echoServer echoes data messages back, the error of the last read is sent to readErr
+func echoServer(t *testing.T) (string, <-chan error) {
+	t.Helper()
+
+	readErr := make(chan error, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			conn.WriteMessage(typ, msg)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://"), readErr
+}
+
+// dial performs handshake with the RFC 6455 sample key
+func dial(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: " + addr + "\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status is not equal, want %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("accept key is not equal, want %s, got %s", "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", accept)
+	}
+
+	return conn, br
+}
+
+// clientFrame encodes frame as client, masked unless mask is nil
+func clientFrame(fin bool, opcode byte, payload []byte, mask []byte) []byte {
+	b := opcode
+	if fin {
+		b |= 0x80
+	}
+	frame := []byte{b}
+
+	maskBit := byte(0)
+	if mask != nil {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	if mask == nil {
+		return append(frame, payload...)
+	}
+
+	frame = append(frame, mask...)
+	for i, c := range payload {
+		frame = append(frame, c^mask[i%4])
+	}
+
+	return frame
+}
+
+// readFrame reads unmasked server frame
+func readFrame(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+
+	if header[0]&0x80 == 0 || header[1]&0x80 != 0 {
+		t.Fatalf("server frame must be final and unmasked, got header %08b %08b", header[0], header[1])
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		io.ReadFull(br, ext)
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		io.ReadFull(br, ext)
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("failed to read payload: %v", err)
+	}
+
+	return header[0] & 0x0f, payload
+}
+
+func closeCode(payload []byte) int {
+	if len(payload) < 2 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func Test_Conn(t *testing.T) {
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	long := bytes.Repeat([]byte("0123456789"), 1000)
+
+	testCases := []struct {
+		desc   string
+		frames [][]byte
+		// frames expected from server by opcode and payload
+		wantOpcodes  []byte
+		wantPayloads [][]byte
+		wantClose    int
+	}{
+		{
+			desc:         "Masked text message",
+			frames:       [][]byte{clientFrame(true, websocket.TextMessage, []byte("Hello"), mask)},
+			wantOpcodes:  []byte{websocket.TextMessage},
+			wantPayloads: [][]byte{[]byte("Hello")},
+		},
+		{
+			desc:         "Extended 16 bit length",
+			frames:       [][]byte{clientFrame(true, websocket.BinaryMessage, long, mask)},
+			wantOpcodes:  []byte{websocket.BinaryMessage},
+			wantPayloads: [][]byte{long},
+		},
+		{
+			desc: "Fragmented message with interleaved ping",
+			frames: [][]byte{
+				clientFrame(false, websocket.TextMessage, []byte("Hel"), mask),
+				clientFrame(true, websocket.PingMessage, []byte("ping"), mask),
+				clientFrame(true, 0, []byte("lo"), mask),
+			},
+			wantOpcodes:  []byte{websocket.PongMessage, websocket.TextMessage},
+			wantPayloads: [][]byte{[]byte("ping"), []byte("Hello")},
+		},
+		{
+			desc:        "Unmasked frame",
+			frames:      [][]byte{clientFrame(true, websocket.TextMessage, []byte("Hello"), nil)},
+			wantOpcodes: []byte{websocket.CloseMessage},
+			wantClose:   websocket.CloseProtocolError,
+		},
+		{
+			desc:        "Unexpected continuation frame",
+			frames:      [][]byte{clientFrame(true, 0, []byte("lo"), mask)},
+			wantOpcodes: []byte{websocket.CloseMessage},
+			wantClose:   websocket.CloseProtocolError,
+		},
+		{
+			desc:        "Message too big",
+			frames:      [][]byte{clientFrame(true, websocket.BinaryMessage, bytes.Repeat(long, 7), mask)},
+			wantOpcodes: []byte{websocket.CloseMessage},
+			wantClose:   websocket.CloseMessageTooBig,
+		},
+		{
+			desc:        "Close is echoed",
+			frames:      [][]byte{clientFrame(true, websocket.CloseMessage, binary.BigEndian.AppendUint16(nil, websocket.CloseGoingAway), mask)},
+			wantOpcodes: []byte{websocket.CloseMessage},
+			wantClose:   websocket.CloseGoingAway,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			addr, readErr := echoServer(t)
+			conn, br := dial(t, addr)
+
+			for _, frame := range tC.frames {
+				if _, err := conn.Write(frame); err != nil {
+					t.Fatalf("failed to write frame: %v", err)
+				}
+			}
+
+			for i, want := range tC.wantOpcodes {
+				opcode, payload := readFrame(t, br)
+				if opcode != want {
+					t.Fatalf("opcode is not equal, want %d, got %d", want, opcode)
+				}
+
+				if opcode == websocket.CloseMessage {
+					if code := closeCode(payload); code != tC.wantClose {
+						t.Fatalf("close code is not equal, want %d, got %d", tC.wantClose, code)
+					}
+					continue
+				}
+
+				if !bytes.Equal(tC.wantPayloads[i], payload) {
+					t.Fatalf("payload is not equal, want %d bytes, got %d bytes", len(tC.wantPayloads[i]), len(payload))
+				}
+			}
+
+			if tC.wantClose == 0 {
+				return
+			}
+
+			err := <-readErr
+			var closeErr *websocket.CloseError
+			if tC.wantClose == websocket.CloseGoingAway && (!errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway) {
+				t.Fatalf("read error is not equal, want close code %d, got %v", websocket.CloseGoingAway, err)
+			}
+		})
+	}
+}